@@ -0,0 +1,3 @@
+// Package admin contains the admin HTTP API
+// used to inspect and control the daemon.
+package admin
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/daemon"
+)
+
+var gServer *http.Server
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		glog.Errorf("Got error when writing admin API response: %v", err)
+	}
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("OK"))
+}
+
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !daemon.Ready() {
+		http.Error(w, "No configuration rendered yet", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	w.Write([]byte("OK"))
+}
+
+func handleServices(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, daemon.CurrentServices())
+}
+
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(daemon.CurrentConfiguration()))
+}
+
+func handleErrors(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, daemon.ParseErrors())
+}
+
+func handleDrift(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, daemon.LabelDrifts())
+}
+
+func handleRefresh(w http.ResponseWriter, r *http.Request) {
+	glog.Infoln("Received a refresh request from the admin API, doing manual configuration reload...")
+
+	daemon.RequestRefresh()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	mux.HandleFunc("GET /readyz", handleReadyz)
+	mux.HandleFunc("GET /v1/services", handleServices)
+	mux.HandleFunc("GET /v1/config", handleConfig)
+	mux.HandleFunc("GET /v1/errors", handleErrors)
+	mux.HandleFunc("GET /v1/drift", handleDrift)
+	mux.HandleFunc("POST /v1/refresh", handleRefresh)
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	return mux
+}
+
+// Serve starts the admin HTTP API in the background
+// if a listen address is configured.
+func Serve(config *configuration.Config) {
+	if config.Admin.ListenAddress == "" {
+		return
+	}
+
+	gServer = &http.Server{
+		Addr:              config.Admin.ListenAddress,
+		Handler:           newHandler(),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	go func() {
+		glog.Infof("Starting admin API on %s", config.Admin.ListenAddress)
+
+		if err := gServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			glog.Errorf("Got error when serving admin API: %v", err)
+		}
+	}()
+}
+
+// Shutdown gracefully stops the admin HTTP API.
+func Shutdown() {
+	if gServer == nil {
+		return
+	}
+
+	glog.Infoln("Shutting down admin API...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err := gServer.Shutdown(ctx); err != nil {
+		glog.Errorf("Got error when shutting down admin API: %v", err)
+	}
+}
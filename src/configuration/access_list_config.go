@@ -0,0 +1,56 @@
+package configuration
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+const (
+	// ClientAddressConnection takes the address of clients
+	// from their connection.
+	ClientAddressConnection = "connection"
+
+	// ClientAddressForwardedFor takes the address of clients from
+	// the last X-Forwarded-For header added by a trusted proxy.
+	ClientAddressForwardedFor = "x-forwarded-for"
+
+	// ClientAddressProxyProtocol takes the address of clients from
+	// the PROXY protocol header sent by a trusted proxy.
+	ClientAddressProxyProtocol = "proxy-protocol"
+)
+
+// AccessListsConfig is the configuration for the files
+// the daemon writes large allow and deny lists to.
+type AccessListsConfig struct {
+	// Directory is the directory where the files of allow and
+	// deny lists are written, files no longer used are removed.
+	//
+	// Defaults to the acls directory next to OutputFilePath
+	Directory string `json:"directory" yaml:"directory" toml:"directory"`
+
+	// InlineLimit is the maximum number of entries of a list
+	// rendered inline, larger lists are written to files.
+	//
+	// Defaults to 10
+	InlineLimit *int `json:"inlineLimit" yaml:"inline_limit" toml:"inline_limit"`
+}
+
+// ValidateAddresses validates a list of IP addresses or CIDRs.
+func ValidateAddresses(addresses []string) error {
+	for _, address := range addresses {
+		if strings.Contains(address, "/") {
+			if _, err := netip.ParsePrefix(address); err != nil {
+				return fmt.Errorf("invalid CIDR %s", address)
+			}
+
+			continue
+		}
+
+		if _, err := netip.ParseAddr(address); err != nil {
+			return fmt.Errorf("invalid IP address %s", address)
+		}
+	}
+
+	return nil
+}
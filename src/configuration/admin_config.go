@@ -0,0 +1,12 @@
+package configuration
+
+// AdminConfig is the configuration for
+// the admin HTTP API, which also serves
+// the Prometheus metrics on /metrics.
+type AdminConfig struct {
+	// ListenAddress is the address the admin HTTP API listens on,
+	// e.g. "127.0.0.1:8080".
+	//
+	// The admin HTTP API is disabled if empty.
+	ListenAddress string `json:"listenAddress" yaml:"listen_address" toml:"listen_address"`
+}
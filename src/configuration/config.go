@@ -11,6 +11,13 @@ type Config struct {
 	// Defaults to "haproxy"
 	Prefix string `json:"prefix" yaml:"prefix" toml:"prefix"`
 
+	// LabelDriftPolicy determines which labels are used when
+	// the instances of a service disagree on their labels.
+	//
+	// One of: majority, newest, fail
+	// Default: majority
+	LabelDriftPolicy string `json:"labelDriftPolicy" yaml:"label_drift_policy" toml:"label_drift_policy"`
+
 	// TemplateFilePath is the path to the templated file
 	// that will be used to build the final HAProxy configuration.
 	//
@@ -36,6 +43,19 @@ type Config struct {
 	// per entrypoint.
 	Entrypoints map[string]*EntrypointConfig `json:"entryPoints" yaml:"entrypoints" toml:"entrypoints"`
 
+	// AccessLists is the configuration for the files of
+	// large allow and deny lists.
+	AccessLists *AccessListsConfig `json:"accessLists" yaml:"access_lists" toml:"access_lists"`
+
+	// Userlists is a map of userlist name to the userlists
+	// services can require HTTP basic auth against.
+	Userlists map[string]*UserlistConfig `json:"userlists" yaml:"userlists" toml:"userlists"`
+
+	// ResponseHeaderPresets is a map of preset name to the response
+	// headers services can opt into, in addition to the built-in
+	// presets of DefaultResponseHeaderPresets.
+	ResponseHeaderPresets map[string]map[string]string `json:"responseHeaderPresets" yaml:"response_header_presets" toml:"response_header_presets"`
+
 	// HealthChecks represents the individual health check config
 	// for a service, or a default configuration for all services.
 	HealthChecks map[string]*HealthCheckConfig `json:"healthChecks" yaml:"health_checks" toml:"health_checks"`
@@ -43,9 +63,30 @@ type Config struct {
 	// ServersConfig is the configuration for both default-server and per server.
 	ServersConfig *ServersConfig `json:"servers" yaml:"servers" toml:"servers"`
 
+	// Providers is the list of service discovery providers
+	// to fetch services from, one of: consul, nomad, dns, file.
+	//
+	// Defaults to ["consul"]
+	Providers []string `json:"providers" yaml:"providers" toml:"providers"`
+
+	// File represents the file provider configuration options.
+	File *FileProviderConfig `json:"file" yaml:"file" toml:"file"`
+
+	// DNS represents the DNS SRV configuration options.
+	DNS *DNSConfig `json:"dns" yaml:"dns" toml:"dns"`
+
+	// Nomad represents the Nomad configuration options.
+	Nomad *NomadConfig `json:"nomad" yaml:"nomad" toml:"nomad"`
+
 	// Consul represents the Consul configuration options.
 	Consul *ConsulConfig `json:"consul" yaml:"consul" toml:"consul"`
 
 	// HAProxy represents the HAProxy configuration options.
 	HAProxy *HAProxyConfig `json:"haproxy" yaml:"haproxy" toml:"haproxy"`
+
+	// Admin represents the admin HTTP API configuration options.
+	Admin *AdminConfig `json:"admin" yaml:"admin" toml:"admin"`
+
+	// Recording represents the Catalog recording configuration options.
+	Recording *RecordingConfig `json:"recording" yaml:"recording" toml:"recording"`
 }
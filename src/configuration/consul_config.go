@@ -51,6 +51,44 @@ type ConsulTLSConfig struct {
 	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecure_skip_verify" toml:"insecure_skip_verify"`
 }
 
+const (
+	// ConsulHealthModeCatalog discovers every registered instance,
+	// ignoring Consul health checks.
+	ConsulHealthModeCatalog = "catalog"
+
+	// ConsulHealthModePassing only routes to instances
+	// with all health checks passing.
+	ConsulHealthModePassing = "passing"
+
+	// ConsulHealthModeWarning routes to instances with
+	// health checks either passing or warning.
+	ConsulHealthModeWarning = "warning"
+
+	// ConsulCriticalInstancesOmit leaves unhealthy instances out
+	// of the rendered backends.
+	ConsulCriticalInstancesOmit = "omit"
+
+	// ConsulCriticalInstancesDisabled renders unhealthy instances
+	// with the "disabled" server option.
+	ConsulCriticalInstancesDisabled = "disabled"
+
+	// ConsulCriticalInstancesDown renders unhealthy instances
+	// with the "init-state down" server option.
+	ConsulCriticalInstancesDown = "down"
+)
+
+// ConsulPreparedQueryConfig represents a service
+// whose nodes come from a Consul prepared query.
+type ConsulPreparedQueryConfig struct {
+	// Query is the name or ID of the prepared query to execute.
+	Query string `json:"query" yaml:"query" toml:"query"`
+
+	// Labels are the routing labels of the service, without the
+	// prefix, e.g. fe.fqdn. If not provided, the labels are parsed
+	// from the tags of the instances returned by the query.
+	Labels map[string]string `json:"labels" yaml:"labels" toml:"labels"`
+}
+
 // ConsulConfig represents the configuration
 // for Consul service discovery.
 // Pretty much just config options for the API client.
@@ -69,6 +107,20 @@ type ConsulConfig struct {
 	// Datacenter to use. If not provided, the default agent datacenter is used.
 	Datacenter string `json:"datacenter" yaml:"datacenter" toml:"datacenter"`
 
+	// Datacenters is the list of datacenters to discover services
+	// in, the local Datacenter is always included.
+	//
+	// Nodes in other datacenters are rendered as backup servers,
+	// see CrossDatacenterFailover.
+	Datacenters []string `json:"datacenters" yaml:"datacenters" toml:"datacenters"`
+
+	// CrossDatacenterFailover determines if services fail over to nodes
+	// in other datacenters by default, services can opt in or out with
+	// the be.failover label.
+	//
+	// Defaults to true
+	CrossDatacenterFailover *bool `json:"crossDatacenterFailover" yaml:"cross_datacenter_failover" toml:"cross_datacenter_failover"`
+
 	// HttpAuth is the auth info to use for HTTP access.
 	HttpAuth *ConsulHttpBasicAuth `json:"httpAuth" yaml:"http_auth" toml:"http_auth"`
 
@@ -94,4 +146,67 @@ type ConsulConfig struct {
 
 	// TLSConfig is the configuration for the TLS client.
 	TLSConfig *ConsulTLSConfig `json:"tlsConfig" yaml:"tls_config" toml:"tls_config"`
+
+	// WeightMetaKey is the service metadata key that overrides
+	// the passing weight of an instance, see WarningWeightPercent.
+	//
+	// Defaults to "weight"
+	WeightMetaKey string `json:"weightMetaKey" yaml:"weight_meta_key" toml:"weight_meta_key"`
+
+	// WarningWeightPercent is the percentage of its weight an instance
+	// with health checks in warning keeps, unless the service registers
+	// a Warning weight different from its Passing weight. The weights of
+	// the service are scaled up first, so that instances with the default
+	// weight are reduced too, and 0 drains the instances in warning.
+	//
+	// Weights are only reduced when HealthMode is warning, the only
+	// mode that routes to instances in warning by their health checks.
+	//
+	// Defaults to 50
+	WarningWeightPercent *int `json:"warningWeightPercent" yaml:"warning_weight_percent" toml:"warning_weight_percent"`
+
+	// PreparedQueries is a map of service name to the prepared query its
+	// nodes come from. Services in the Catalog can also declare their
+	// prepared query with the be.preparedQuery label.
+	PreparedQueries map[string]*ConsulPreparedQueryConfig `json:"preparedQueries" yaml:"prepared_queries" toml:"prepared_queries"`
+
+	// PreparedQueryPollInterval is the interval prepared queries
+	// are executed at to detect changes, as they cannot be watched
+	// with blocking queries.
+	//
+	// Defaults to 10s
+	PreparedQueryPollInterval *time.Duration `json:"preparedQueryPollInterval" yaml:"prepared_query_poll_interval" toml:"prepared_query_poll_interval"`
+
+	// Watch determines if the Daemon should long-poll the Catalog
+	// with blocking queries and refresh as soon as a registration
+	// changes, instead of only refreshing on RefreshInterval.
+	//
+	// Defaults to true
+	Watch *bool `json:"watch" yaml:"watch" toml:"watch"`
+
+	// WatchRateLimit is the minimum time between two refreshes
+	// triggered by the Catalog watch.
+	//
+	// Defaults to 2s
+	WatchRateLimit *time.Duration `json:"watchRateLimit" yaml:"watch_rate_limit" toml:"watch_rate_limit"`
+
+	// WatchRetryInterval is the time to wait before retrying
+	// a blocking query that failed.
+	//
+	// Defaults to 5s
+	WatchRetryInterval *time.Duration `json:"watchRetryInterval" yaml:"watch_retry_interval" toml:"watch_retry_interval"`
+
+	// HealthMode determines how Consul health checks are used
+	// when discovering service instances.
+	//
+	// One of: catalog, passing, warning (passing and warning)
+	// Default: catalog
+	HealthMode string `json:"healthMode" yaml:"health_mode" toml:"health_mode"`
+
+	// CriticalInstances determines what happens to instances that
+	// do not satisfy HealthMode.
+	//
+	// One of: omit, disabled, down
+	// Default: omit
+	CriticalInstances string `json:"criticalInstances" yaml:"critical_instances" toml:"critical_instances"`
 }
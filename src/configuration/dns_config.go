@@ -0,0 +1,40 @@
+package configuration
+
+import "time"
+
+// DNSServiceConfig represents a service
+// discovered from a DNS SRV record.
+type DNSServiceConfig struct {
+	// Name is the name of the SRV record to resolve,
+	// e.g. _http._tcp.legacy.service.consul
+	Name string `json:"name" yaml:"name" toml:"name"`
+
+	// Labels are the routing labels of the service, without
+	// the prefix, e.g. fe.fqdn, as DNS records have no tags.
+	Labels map[string]string `json:"labels" yaml:"labels" toml:"labels"`
+}
+
+// DNSConfig represents the configuration
+// for DNS SRV service discovery.
+type DNSConfig struct {
+	// Nameservers are the addresses of the nameservers to query,
+	// in order. The port defaults to 53 if not specified.
+	//
+	// Defaults to the nameservers in /etc/resolv.conf
+	Nameservers []string `json:"nameservers" yaml:"nameservers" toml:"nameservers"`
+
+	// Timeout is the timeout of a single DNS query.
+	//
+	// Defaults to 2s
+	Timeout *time.Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+
+	// MinimumTTL is the minimum time resolved records are
+	// cached for, regardless of their TTL.
+	//
+	// Defaults to 5s
+	MinimumTTL *time.Duration `json:"minimumTtl" yaml:"minimum_ttl" toml:"minimum_ttl"`
+
+	// Services is a map of service name to the SRV record it
+	// is discovered from.
+	Services map[string]*DNSServiceConfig `json:"services" yaml:"services" toml:"services"`
+}
@@ -1,6 +1,10 @@
 package configuration
 
-import "fmt"
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
 
 // HeaderConfig represents a config for a specific header.
 type HeaderConfig struct {
@@ -18,12 +22,46 @@ type EntrypointConfig struct {
 	// RequestHeaders is the request headers
 	// to add to each backend request.
 	RequestHeaders map[string]*HeaderConfig `json:"requestHeaders" yaml:"request_headers" toml:"request_headers"`
+
+	// ResponseHeaders is the response headers
+	// to add to each backend response.
+	ResponseHeaders map[string]*HeaderConfig `json:"responseHeaders" yaml:"response_headers" toml:"response_headers"`
+
+	// DeleteResponseHeaders is the response headers
+	// to delete from each backend response.
+	DeleteResponseHeaders []string `json:"deleteResponseHeaders" yaml:"delete_response_headers" toml:"delete_response_headers"`
+
+	// RateLimit is the default rate limit of the
+	// backends of the services of this entrypoint.
+	RateLimit *RateLimitConfig `json:"rateLimit" yaml:"rate_limit" toml:"rate_limit"`
+
+	// AllowList is the list of IP addresses or CIDRs of the clients
+	// allowed to reach this entrypoint, every client if empty.
+	AllowList []string `json:"allowList" yaml:"allow_list" toml:"allow_list"`
+
+	// DenyList is the list of IP addresses or CIDRs of the
+	// clients denied from reaching this entrypoint.
+	DenyList []string `json:"denyList" yaml:"deny_list" toml:"deny_list"`
+
+	// ClientAddress is where the address of clients is taken from,
+	// one of connection, x-forwarded-for, or proxy-protocol. Allow
+	// and deny lists, as well as rate limits, apply to this address.
+	//
+	// Defaults to connection
+	ClientAddress string `json:"clientAddress" yaml:"client_address" toml:"client_address"`
+
+	// TrustedProxies is the list of IP addresses or CIDRs of the
+	// upstream proxies trusted to forward the address of clients,
+	// required unless ClientAddress is connection.
+	TrustedProxies []string `json:"trustedProxies" yaml:"trusted_proxies" toml:"trusted_proxies"`
 }
 
 func (c *EntrypointConfig) String() string {
 	var result string
 
-	for key, value := range c.RequestHeaders {
+	for _, key := range slices.Sorted(maps.Keys(c.RequestHeaders)) {
+		value := c.RequestHeaders[key]
+
 		if value.AppendValue {
 			result += fmt.Sprintf("  http-request add-header %s %s", key, value.Value)
 		} else {
@@ -33,5 +71,21 @@ func (c *EntrypointConfig) String() string {
 		result += "\n"
 	}
 
+	for _, key := range slices.Sorted(maps.Keys(c.ResponseHeaders)) {
+		value := c.ResponseHeaders[key]
+
+		if value.AppendValue {
+			result += fmt.Sprintf("  http-response add-header %s %s", key, QuoteValue(value.Value))
+		} else {
+			result += fmt.Sprintf("  http-response set-header %s %s", key, QuoteValue(value.Value))
+		}
+
+		result += "\n"
+	}
+
+	for _, header := range c.DeleteResponseHeaders {
+		result += fmt.Sprintf("  http-response del-header %s\n", header)
+	}
+
 	return result
 }
@@ -1,5 +1,41 @@
 package configuration
 
+import "time"
+
+// RuntimeAPIConfig is the configuration for applying server
+// changes through the HAProxy Runtime API instead of reloading.
+type RuntimeAPIConfig struct {
+	// Enabled determines if node changes are applied through
+	// the Runtime API. Backends are then rendered with a fixed
+	// number of server slots that are filled in at runtime.
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled"`
+
+	// SocketAddress is the address of the HAProxy stats or master
+	// socket, which must be declared with "level admin" in the template.
+	// Absolute paths are treated as UNIX sockets, anything else as TCP.
+	//
+	// Defaults to "/var/run/haproxy.sock"
+	SocketAddress string `json:"socketAddress" yaml:"socket_address" toml:"socket_address"`
+
+	// MasterSocket determines if SocketAddress is the master CLI,
+	// in which case commands are forwarded to the current worker,
+	// and HAProxy is reloaded with its reload command, which reports
+	// failed reloads from HAProxy 2.7. Timeout must then leave the
+	// new workers enough time to start.
+	MasterSocket bool `json:"masterSocket" yaml:"master_socket" toml:"master_socket"`
+
+	// Slots is the number of server slots a backend grows by
+	// when it runs out of slots, which requires a reload.
+	//
+	// Defaults to 10
+	Slots int `json:"slots" yaml:"slots" toml:"slots"`
+
+	// Timeout is the timeout of a single Runtime API command.
+	//
+	// Defaults to 5s
+	Timeout time.Duration `json:"timeout" yaml:"timeout" toml:"timeout"`
+}
+
 // HAProxyConfig is all configuration related
 // to the HAProxy process.
 type HAProxyConfig struct {
@@ -30,6 +66,16 @@ type HAProxyConfig struct {
 	// Defaults to "/var/log/haproxy/stderr"
 	StderrLogFilePath string `json:"stderrLogFilePath" yaml:"stderr_log_file_path" toml:"stderr_log_file_path"`
 
+	// LastKnownGoodFilePath is the path where the last configuration
+	// that was successfully validated and reloaded is kept, it is
+	// restored if a new configuration fails to validate or reload.
+	//
+	// Defaults to $OUTPUT_FILE_PATH.last-known-good
+	LastKnownGoodFilePath string `json:"lastKnownGoodFilePath" yaml:"last_known_good_file_path" toml:"last_known_good_file_path"`
+
+	// RuntimeAPI is the configuration for the HAProxy Runtime API.
+	RuntimeAPI *RuntimeAPIConfig `json:"runtimeApi" yaml:"runtime_api" toml:"runtime_api"`
+
 	// MaxStartAttempts is the maximum number of attempts
 	// to start HAProxy before giving up.
 	//
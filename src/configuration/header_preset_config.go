@@ -0,0 +1,54 @@
+package configuration
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultResponseHeaderPresets are the built-in presets of response
+// headers services can opt into, presets of the configuration with
+// the same name replace them.
+var DefaultResponseHeaderPresets = map[string]map[string]string{
+	"hsts": {
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+	},
+	"frame-deny": {
+		"X-Frame-Options": "DENY",
+	},
+	"frame-sameorigin": {
+		"X-Frame-Options": "SAMEORIGIN",
+	},
+	"csp": {
+		"Content-Security-Policy": "default-src 'self'",
+	},
+	"referrer-policy": {
+		"Referrer-Policy": "strict-origin-when-cross-origin",
+	},
+	"nosniff": {
+		"X-Content-Type-Options": "nosniff",
+	},
+	"security": {
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"X-Frame-Options":           "DENY",
+		"Content-Security-Policy":   "default-src 'self'",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"X-Content-Type-Options":    "nosniff",
+	},
+}
+
+// ValidHeaderName determines if a header name can be rendered.
+func ValidHeaderName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n:\"'")
+}
+
+// ValidHeaderValue determines if a header value can be rendered,
+// control characters would break the line or the header.
+func ValidHeaderValue(value string) bool {
+	return !strings.ContainsFunc(value, unicode.IsControl)
+}
+
+// QuoteValue quotes a value, such as a header value, as a single
+// HAProxy argument. Percent signs are escaped as values are log formats.
+func QuoteValue(value string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "%", "%%").Replace(value) + "\""
+}
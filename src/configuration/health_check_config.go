@@ -3,6 +3,7 @@ package configuration
 import (
 	"fmt"
 	"maps"
+	"slices"
 )
 
 // HealthCheckConfig is the configuration for
@@ -145,8 +146,8 @@ func (h *HealthCheckConfig) String() string {
 		if send.Version != "" {
 			result += fmt.Sprintf(" ver %s", send.Version)
 		}
-		for key, val := range send.Headers {
-			result += fmt.Sprintf(" hdr %s %s", key, val)
+		for _, key := range slices.Sorted(maps.Keys(send.Headers)) {
+			result += fmt.Sprintf(" hdr %s %s", key, send.Headers[key])
 		}
 		if send.Body != "" {
 			result += fmt.Sprintf(" body %s", send.Body)
@@ -0,0 +1,68 @@
+package configuration
+
+import "time"
+
+// NomadTLSConfig represents the Nomad API TLS configuration.
+type NomadTLSConfig struct {
+	// CAFile is the optional path to the CA certificate used for Nomad
+	// communication, defaults to the system bundle if not specified.
+	CAFile string `json:"caFile" yaml:"ca_file" toml:"ca_file"`
+
+	// CertFile is the optional path to the certificate for Nomad
+	// communication. If this is set then you need to also set KeyFile.
+	CertFile string `json:"certFile" yaml:"cert_file" toml:"cert_file"`
+
+	// KeyFile is the optional path to the private key for Nomad communication.
+	// If this is set then you need to also set CertFile.
+	KeyFile string `json:"keyFile" yaml:"key_file" toml:"key_file"`
+
+	// InsecureSkipVerify if set to true will disable TLS host verification.
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecure_skip_verify" toml:"insecure_skip_verify"`
+}
+
+// NomadConfig represents the configuration for
+// Nomad native service discovery.
+type NomadConfig struct {
+	// Address is the address of the Nomad HTTP API.
+	//
+	// Defaults to http://127.0.0.1:4646
+	Address string `json:"address" yaml:"address" toml:"address"`
+
+	// Region to use. If not provided, the region of the agent is used.
+	Region string `json:"region" yaml:"region" toml:"region"`
+
+	// Namespace to discover services in, "*" discovers
+	// services in every namespace.
+	//
+	// Defaults to "default"
+	Namespace string `json:"namespace" yaml:"namespace" toml:"namespace"`
+
+	// Token is the ACL token used for requests.
+	Token string `json:"token" yaml:"token" toml:"token"`
+
+	// WaitTime limits how long a watch will block. If not provided,
+	// the agent default values will be used.
+	WaitTime time.Duration `json:"waitTime" yaml:"wait_time" toml:"wait_time"`
+
+	// TLSConfig is the configuration for the TLS client.
+	TLSConfig *NomadTLSConfig `json:"tlsConfig" yaml:"tls_config" toml:"tls_config"`
+
+	// Watch determines if the Daemon should long-poll the service
+	// registrations with blocking queries and refresh as soon as a
+	// registration changes, instead of only refreshing on RefreshInterval.
+	//
+	// Defaults to true
+	Watch *bool `json:"watch" yaml:"watch" toml:"watch"`
+
+	// WatchRateLimit is the minimum time between two refreshes
+	// triggered by the watch.
+	//
+	// Defaults to 2s
+	WatchRateLimit *time.Duration `json:"watchRateLimit" yaml:"watch_rate_limit" toml:"watch_rate_limit"`
+
+	// WatchRetryInterval is the time to wait before retrying
+	// a blocking query that failed.
+	//
+	// Defaults to 5s
+	WatchRetryInterval *time.Duration `json:"watchRetryInterval" yaml:"watch_retry_interval" toml:"watch_retry_interval"`
+}
@@ -1,11 +1,13 @@
 package configuration
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -17,8 +19,13 @@ import (
 const (
 	DefaultLabelPrefix    = "haproxy"
 	DefaultOutputFilePath = "/usr/local/etc/haproxy/haproxy.cfg"
+	DefaultNomadAddress   = "http://127.0.0.1:4646"
+	DefaultNomadNamespace = "default"
 )
 
+// userlistNamePattern matches the names HAProxy accepts for userlists.
+var userlistNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
 func parseYAMLFile(fileName string) (*Config, error) {
 	var conf Config
 
@@ -77,6 +84,16 @@ func applyDefaultsAndValidateConfiguration(config *Config) error {
 		config.Prefix = DefaultLabelPrefix
 	}
 
+	if config.LabelDriftPolicy == "" {
+		config.LabelDriftPolicy = LabelDriftPolicyMajority
+	}
+
+	switch config.LabelDriftPolicy {
+	case LabelDriftPolicyMajority, LabelDriftPolicyNewest, LabelDriftPolicyFail:
+	default:
+		return fmt.Errorf("config.LabelDriftPolicy must be one of majority, newest, or fail, got %s", config.LabelDriftPolicy)
+	}
+
 	if config.TemplateFilePath == "" {
 		return fmt.Errorf("config.TemplateFilePath must be specified!")
 	}
@@ -110,6 +127,150 @@ func applyDefaultsAndValidateConfiguration(config *Config) error {
 		*config.RefreshInterval = time.Minute * 5
 	}
 
+	if len(config.Providers) == 0 {
+		config.Providers = []string{ProviderConsul}
+	}
+
+	for _, provider := range config.Providers {
+		switch provider {
+		case ProviderConsul, ProviderNomad, ProviderDNS, ProviderFile:
+		default:
+			return fmt.Errorf("config.Providers entries must be one of consul, nomad, dns, or file, got %s", provider)
+		}
+	}
+
+	if config.File == nil {
+		config.File = new(FileProviderConfig)
+	}
+
+	if config.File.Directory != "" && !filepath.IsAbs(config.File.Directory) {
+		absPath, err := filepath.Abs(config.File.Directory)
+		if err != nil {
+			return err
+		}
+		config.File.Directory = absPath
+	}
+
+	if config.File.PollInterval == nil {
+		config.File.PollInterval = new(time.Duration)
+		*config.File.PollInterval = time.Second * 5
+	}
+
+	if config.DNS == nil {
+		config.DNS = new(DNSConfig)
+	}
+
+	if config.DNS.Timeout == nil {
+		config.DNS.Timeout = new(time.Duration)
+		*config.DNS.Timeout = time.Second * 2
+	}
+
+	if config.DNS.MinimumTTL == nil {
+		config.DNS.MinimumTTL = new(time.Duration)
+		*config.DNS.MinimumTTL = time.Second * 5
+	}
+
+	for serviceName, service := range config.DNS.Services {
+		if service == nil || service.Name == "" {
+			return fmt.Errorf("config.DNS.Services[%s].Name must be specified!", serviceName)
+		}
+	}
+
+	if config.Nomad == nil {
+		config.Nomad = new(NomadConfig)
+	}
+
+	if config.Nomad.Address == "" {
+		config.Nomad.Address = DefaultNomadAddress
+	}
+
+	if config.Nomad.Namespace == "" {
+		config.Nomad.Namespace = DefaultNomadNamespace
+	}
+
+	if config.Nomad.Watch == nil {
+		config.Nomad.Watch = new(bool)
+		*config.Nomad.Watch = true
+	}
+
+	if config.Nomad.WatchRateLimit == nil {
+		config.Nomad.WatchRateLimit = new(time.Duration)
+		*config.Nomad.WatchRateLimit = time.Second * 2
+	}
+
+	if config.Nomad.WatchRetryInterval == nil {
+		config.Nomad.WatchRetryInterval = new(time.Duration)
+		*config.Nomad.WatchRetryInterval = time.Second * 5
+	}
+
+	if config.Consul == nil {
+		config.Consul = new(ConsulConfig)
+	}
+
+	if config.Consul.CrossDatacenterFailover == nil {
+		config.Consul.CrossDatacenterFailover = new(bool)
+		*config.Consul.CrossDatacenterFailover = true
+	}
+
+	if config.Consul.WeightMetaKey == "" {
+		config.Consul.WeightMetaKey = "weight"
+	}
+
+	if config.Consul.WarningWeightPercent == nil {
+		config.Consul.WarningWeightPercent = new(int)
+		*config.Consul.WarningWeightPercent = 50
+	}
+
+	if *config.Consul.WarningWeightPercent < 0 || *config.Consul.WarningWeightPercent > 100 {
+		return fmt.Errorf("config.Consul.WarningWeightPercent must be between 0 and 100, got %d", *config.Consul.WarningWeightPercent)
+	}
+
+	if config.Consul.PreparedQueryPollInterval == nil {
+		config.Consul.PreparedQueryPollInterval = new(time.Duration)
+		*config.Consul.PreparedQueryPollInterval = time.Second * 10
+	}
+
+	for serviceName, preparedQuery := range config.Consul.PreparedQueries {
+		if preparedQuery == nil || preparedQuery.Query == "" {
+			return fmt.Errorf("config.Consul.PreparedQueries[%s].Query must be specified!", serviceName)
+		}
+	}
+
+	if config.Consul.Watch == nil {
+		config.Consul.Watch = new(bool)
+		*config.Consul.Watch = true
+	}
+
+	if config.Consul.WatchRateLimit == nil {
+		config.Consul.WatchRateLimit = new(time.Duration)
+		*config.Consul.WatchRateLimit = time.Second * 2
+	}
+
+	if config.Consul.WatchRetryInterval == nil {
+		config.Consul.WatchRetryInterval = new(time.Duration)
+		*config.Consul.WatchRetryInterval = time.Second * 5
+	}
+
+	if config.Consul.HealthMode == "" {
+		config.Consul.HealthMode = ConsulHealthModeCatalog
+	}
+
+	switch config.Consul.HealthMode {
+	case ConsulHealthModeCatalog, ConsulHealthModePassing, ConsulHealthModeWarning:
+	default:
+		return fmt.Errorf("config.Consul.HealthMode must be one of catalog, passing, or warning, got %s", config.Consul.HealthMode)
+	}
+
+	if config.Consul.CriticalInstances == "" {
+		config.Consul.CriticalInstances = ConsulCriticalInstancesOmit
+	}
+
+	switch config.Consul.CriticalInstances {
+	case ConsulCriticalInstancesOmit, ConsulCriticalInstancesDisabled, ConsulCriticalInstancesDown:
+	default:
+		return fmt.Errorf("config.Consul.CriticalInstances must be one of omit, disabled, or down, got %s", config.Consul.CriticalInstances)
+	}
+
 	if config.HAProxy == nil {
 		config.HAProxy = new(HAProxyConfig)
 	}
@@ -117,7 +278,12 @@ func applyDefaultsAndValidateConfiguration(config *Config) error {
 	if config.HAProxy.Path == "" {
 		haproxyPath, err := exec.LookPath("haproxy")
 		if err != nil {
-			return err
+			// Rendering never runs HAProxy.
+			if flag.Arg(0) != flags.RenderCommand {
+				return err
+			}
+
+			haproxyPath = "haproxy"
 		}
 
 		config.HAProxy.Path = haproxyPath
@@ -127,6 +293,34 @@ func applyDefaultsAndValidateConfiguration(config *Config) error {
 		config.HAProxy.Args = append(config.HAProxy.Args, "-W", "-db", "-f", config.OutputFilePath)
 	}
 
+	if config.HAProxy.LastKnownGoodFilePath == "" {
+		config.HAProxy.LastKnownGoodFilePath = config.OutputFilePath + ".last-known-good"
+	}
+
+	if !filepath.IsAbs(config.HAProxy.LastKnownGoodFilePath) {
+		absPath, err := filepath.Abs(config.HAProxy.LastKnownGoodFilePath)
+		if err != nil {
+			return err
+		}
+		config.HAProxy.LastKnownGoodFilePath = absPath
+	}
+
+	if config.HAProxy.RuntimeAPI == nil {
+		config.HAProxy.RuntimeAPI = new(RuntimeAPIConfig)
+	}
+
+	if config.HAProxy.RuntimeAPI.SocketAddress == "" {
+		config.HAProxy.RuntimeAPI.SocketAddress = "/var/run/haproxy.sock"
+	}
+
+	if config.HAProxy.RuntimeAPI.Slots <= 0 {
+		config.HAProxy.RuntimeAPI.Slots = 10
+	}
+
+	if config.HAProxy.RuntimeAPI.Timeout == 0 {
+		config.HAProxy.RuntimeAPI.Timeout = time.Second * 5
+	}
+
 	if config.HAProxy.MaxStartAttempts == nil {
 		config.HAProxy.MaxStartAttempts = new(int)
 		*config.HAProxy.MaxStartAttempts = 3
@@ -140,6 +334,91 @@ func applyDefaultsAndValidateConfiguration(config *Config) error {
 		config.HAProxy.StderrLogFilePath = "/var/log/haproxy/stderr"
 	}
 
+	if config.Admin == nil {
+		config.Admin = new(AdminConfig)
+	}
+
+	if config.Recording == nil {
+		config.Recording = new(RecordingConfig)
+	}
+
+	if config.Recording.MaxSnapshots <= 0 {
+		config.Recording.MaxSnapshots = 100
+	}
+
+	if config.Recording.Directory != "" && !filepath.IsAbs(config.Recording.Directory) {
+		absPath, err := filepath.Abs(config.Recording.Directory)
+		if err != nil {
+			return err
+		}
+		config.Recording.Directory = absPath
+	}
+
+	if config.AccessLists == nil {
+		config.AccessLists = new(AccessListsConfig)
+	}
+
+	if config.AccessLists.Directory == "" {
+		config.AccessLists.Directory = filepath.Join(filepath.Dir(config.OutputFilePath), "acls")
+	}
+
+	if !filepath.IsAbs(config.AccessLists.Directory) {
+		absPath, err := filepath.Abs(config.AccessLists.Directory)
+		if err != nil {
+			return err
+		}
+		config.AccessLists.Directory = absPath
+	}
+
+	if config.AccessLists.InlineLimit == nil {
+		config.AccessLists.InlineLimit = new(int)
+		*config.AccessLists.InlineLimit = 10
+	}
+
+	if *config.AccessLists.InlineLimit < 0 {
+		return fmt.Errorf("config.AccessLists.InlineLimit must not be negative!")
+	}
+
+	if config.ResponseHeaderPresets == nil {
+		config.ResponseHeaderPresets = make(map[string]map[string]string)
+	}
+
+	for name, headers := range config.ResponseHeaderPresets {
+		for header, value := range headers {
+			if !ValidHeaderName(header) {
+				return fmt.Errorf("config.ResponseHeaderPresets.%s has an invalid header %q!", name, header)
+			}
+
+			if !ValidHeaderValue(value) {
+				return fmt.Errorf("config.ResponseHeaderPresets.%s has an invalid value %q for header %s!", name, value, header)
+			}
+		}
+	}
+
+	for name, headers := range DefaultResponseHeaderPresets {
+		if _, ok := config.ResponseHeaderPresets[name]; !ok {
+			config.ResponseHeaderPresets[name] = headers
+		}
+	}
+
+	for name, userlist := range config.Userlists {
+		if !userlistNamePattern.MatchString(name) {
+			return fmt.Errorf("config.Userlists names must only contain letters, digits, -, _, ., or :, got %s", name)
+		}
+
+		if userlist == nil || (len(userlist.Users) == 0 && userlist.File == "" && userlist.ConsulKeyPrefix == "") {
+			return fmt.Errorf("config.Userlists.%s must have at least one of Users, File, or ConsulKeyPrefix!", name)
+		}
+
+		if userlist.File != "" && !filepath.IsAbs(userlist.File) {
+			absPath, err := filepath.Abs(userlist.File)
+			if err != nil {
+				return err
+			}
+			userlist.File = absPath
+		}
+	}
+
 	if config.ServersConfig == nil {
 		config.ServersConfig = new(ServersConfig)
 	}
@@ -182,6 +461,77 @@ func applyDefaultsAndValidateConfiguration(config *Config) error {
 		return fmt.Errorf("config.Entrypoints must have at least one entry!")
 	}
 
+	for name, entrypoint := range config.Entrypoints {
+		if entrypoint == nil {
+			entrypoint = new(EntrypointConfig)
+			config.Entrypoints[name] = entrypoint
+		}
+
+		for header, value := range entrypoint.ResponseHeaders {
+			if !ValidHeaderName(header) || value == nil {
+				return fmt.Errorf("config.Entrypoints.%s.ResponseHeaders has an invalid header %q!", name, header)
+			}
+
+			if !ValidHeaderValue(value.Value) {
+				return fmt.Errorf("config.Entrypoints.%s.ResponseHeaders has an invalid value %q for header %s!", name, value.Value, header)
+			}
+		}
+
+		for _, header := range entrypoint.DeleteResponseHeaders {
+			if !ValidHeaderName(header) {
+				return fmt.Errorf("config.Entrypoints.%s.DeleteResponseHeaders has an invalid header %q!", name, header)
+			}
+		}
+
+		if err := ValidateAddresses(entrypoint.AllowList); err != nil {
+			return fmt.Errorf("config.Entrypoints.%s.AllowList has an %v!", name, err)
+		}
+
+		if err := ValidateAddresses(entrypoint.DenyList); err != nil {
+			return fmt.Errorf("config.Entrypoints.%s.DenyList has an %v!", name, err)
+		}
+
+		if err := ValidateAddresses(entrypoint.TrustedProxies); err != nil {
+			return fmt.Errorf("config.Entrypoints.%s.TrustedProxies has an %v!", name, err)
+		}
+
+		if entrypoint.ClientAddress == "" {
+			entrypoint.ClientAddress = ClientAddressConnection
+		}
+
+		if entrypoint.ClientAddress != ClientAddressConnection &&
+			entrypoint.ClientAddress != ClientAddressForwardedFor &&
+			entrypoint.ClientAddress != ClientAddressProxyProtocol {
+			return fmt.Errorf("config.Entrypoints.%s.ClientAddress must be one of connection, x-forwarded-for, or proxy-protocol, got %s", name, entrypoint.ClientAddress)
+		}
+
+		if entrypoint.ClientAddress != ClientAddressConnection && len(entrypoint.TrustedProxies) == 0 {
+			return fmt.Errorf("config.Entrypoints.%s.TrustedProxies must have at least one entry when ClientAddress is %s!", name, entrypoint.ClientAddress)
+		}
+
+		if entrypoint.RateLimit == nil {
+			continue
+		}
+
+		if entrypoint.RateLimit.Requests != nil && *entrypoint.RateLimit.Requests < 0 {
+			return fmt.Errorf("config.Entrypoints.%s.RateLimit.Requests must not be negative!", name)
+		}
+
+		if entrypoint.RateLimit.Period != nil && *entrypoint.RateLimit.Period < time.Millisecond {
+			return fmt.Errorf("config.Entrypoints.%s.RateLimit.Period must be at least 1ms!", name)
+		}
+
+		if entrypoint.RateLimit.Key != "" {
+			if _, ok := RateLimitKeyFetch(entrypoint.RateLimit.Key); !ok {
+				return fmt.Errorf("config.Entrypoints.%s.RateLimit.Key must be one of src, header:<name>, or cookie:<name>, got %s", name, entrypoint.RateLimit.Key)
+			}
+		}
+
+		if entrypoint.RateLimit.Status != nil && (*entrypoint.RateLimit.Status < 400 || *entrypoint.RateLimit.Status > 599) {
+			return fmt.Errorf("config.Entrypoints.%s.RateLimit.Status must be a 4xx or 5xx status!", name)
+		}
+	}
+
 	return nil
 }
 
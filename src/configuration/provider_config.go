@@ -0,0 +1,48 @@
+package configuration
+
+import "time"
+
+const (
+	// LabelDriftPolicyMajority uses the labels shared
+	// by the most instances of a service.
+	LabelDriftPolicyMajority = "majority"
+
+	// LabelDriftPolicyNewest uses the labels of the
+	// most recently modified instance of a service.
+	LabelDriftPolicyNewest = "newest"
+
+	// LabelDriftPolicyFail skips services whose
+	// instances disagree on their labels.
+	LabelDriftPolicyFail = "fail"
+)
+
+const (
+	// ProviderConsul discovers services from the Consul Catalog.
+	ProviderConsul = "consul"
+
+	// ProviderNomad discovers services from Nomad native service discovery.
+	ProviderNomad = "nomad"
+
+	// ProviderDNS discovers services from DNS SRV records.
+	ProviderDNS = "dns"
+
+	// ProviderFile discovers services from a directory of Json or Yaml files.
+	ProviderFile = "file"
+)
+
+// FileProviderConfig represents the configuration
+// for file service discovery.
+type FileProviderConfig struct {
+	// Directory is the directory of Json or Yaml files to read
+	// services from. Each file is a map of service name to the
+	// list of its instances, with the same tags as in Consul.
+	//
+	// This is required when using the file provider.
+	Directory string `json:"directory" yaml:"directory" toml:"directory"`
+
+	// PollInterval is the interval the directory
+	// is checked for changes at.
+	//
+	// Defaults to 5s
+	PollInterval *time.Duration `json:"pollInterval" yaml:"poll_interval" toml:"poll_interval"`
+}
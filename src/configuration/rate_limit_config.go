@@ -0,0 +1,63 @@
+package configuration
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// RateLimitKeySource tracks clients by their source address.
+	RateLimitKeySource = "src"
+
+	// RateLimitKeyHeaderPrefix tracks clients by the value of a
+	// request header, e.g. header:X-Api-Key
+	RateLimitKeyHeaderPrefix = "header:"
+
+	// RateLimitKeyCookiePrefix tracks clients by the value of a
+	// request cookie, e.g. cookie:session
+	RateLimitKeyCookiePrefix = "cookie:"
+)
+
+// RateLimitConfig represents the default rate limit
+// of the services of an entrypoint, services can
+// override every field with labels.
+type RateLimitConfig struct {
+	// Requests is the number of requests a client may make
+	// per period, clients are not limited if 0.
+	Requests *int `json:"requests" yaml:"requests" toml:"requests"`
+
+	// Period is the period over which requests are counted.
+	//
+	// Defaults to 10s
+	Period *time.Duration `json:"period" yaml:"period" toml:"period"`
+
+	// Key is what clients are tracked by, one of src,
+	// header:<name>, or cookie:<name>.
+	//
+	// Defaults to src
+	Key string `json:"key" yaml:"key" toml:"key"`
+
+	// Status is the status of the responses to
+	// requests over the limit.
+	//
+	// Defaults to 429
+	Status *int `json:"status" yaml:"status" toml:"status"`
+}
+
+// RateLimitKeyFetch gets the HAProxy sample fetch of a
+// rate limit key, or false if the key is invalid.
+func RateLimitKeyFetch(key string) (string, bool) {
+	if key == RateLimitKeySource {
+		return "src", true
+	}
+
+	if name, ok := strings.CutPrefix(key, RateLimitKeyHeaderPrefix); ok && name != "" {
+		return "req.hdr(" + name + ")", true
+	}
+
+	if name, ok := strings.CutPrefix(key, RateLimitKeyCookiePrefix); ok && name != "" {
+		return "req.cook(" + name + ")", true
+	}
+
+	return "", false
+}
@@ -0,0 +1,17 @@
+package configuration
+
+// RecordingConfig is the configuration for recording
+// every fetched Catalog snapshot and its rendered output.
+type RecordingConfig struct {
+	// Directory is the directory where snapshots are recorded,
+	// each snapshot is written to a timestamped sub-directory.
+	//
+	// Recording is disabled if empty.
+	Directory string `json:"directory" yaml:"directory" toml:"directory"`
+
+	// MaxSnapshots is the maximum number of snapshots to keep,
+	// the oldest snapshots are removed first.
+	//
+	// Defaults to 100
+	MaxSnapshots int `json:"maxSnapshots" yaml:"max_snapshots" toml:"max_snapshots"`
+}
@@ -0,0 +1,21 @@
+package configuration
+
+// UserlistConfig is the configuration of a userlist for HTTP
+// basic auth, the users of every source are merged.
+//
+// Passwords are hashed in any format supported by crypt(3) on
+// the HAProxy host, such as SHA-512 ($6$) or bcrypt ($2y$).
+type UserlistConfig struct {
+	// Users is a map of user name to password hash.
+	Users map[string]string `json:"users" yaml:"users" toml:"users"`
+
+	// File is the path to an htpasswd file of users,
+	// with one user:hash per line. It is read again
+	// on every refresh.
+	File string `json:"file" yaml:"file" toml:"file"`
+
+	// ConsulKeyPrefix is a Consul KV prefix of users, every key
+	// under it is a user name whose value is the password hash.
+	// It is read again on every refresh.
+	ConsulKeyPrefix string `json:"consulKeyPrefix" yaml:"consul_key_prefix" toml:"consul_key_prefix"`
+}
@@ -2,54 +2,146 @@ package daemon
 
 import (
 	"context"
-	"os"
+	"slices"
+	"time"
 
 	"github.com/golang/glog"
 	"github.rbx.com/roblox/roblox-load-balancer/configuration"
 	"github.rbx.com/roblox/roblox-load-balancer/haproxy"
+	"github.rbx.com/roblox/roblox-load-balancer/metrics"
+	"github.rbx.com/roblox/roblox-load-balancer/providers"
 	"github.rbx.com/roblox/roblox-load-balancer/services"
 	"github.rbx.com/roblox/roblox-load-balancer/services/types"
 )
 
-// UpdateHAProxyConfigurationFile updates the HAProxy configuration file
-// from Consul and returns the current services.
-func UpdateHAProxyConfigurationFile(ctx context.Context, config *configuration.Config) ([]*types.Service, error) {
-	glog.V(100).Infoln("Trying to update HAProxy configuration from Consul.")
+func recordDiscoveredServices(svcs []*types.Service, config *configuration.Config) {
+	for entryPoint := range config.Entrypoints {
+		serviceCount, nodeCount := 0, 0
+
+		for _, service := range svcs {
+			if !slices.Contains(service.Config.Fe.EntryPoints, entryPoint) {
+				continue
+			}
+
+			serviceCount++
+			nodeCount += len(service.Nodes)
+		}
+
+		metrics.DiscoveredServices.WithLabelValues(entryPoint).Set(float64(serviceCount))
+		metrics.DiscoveredNodes.WithLabelValues(entryPoint).Set(float64(nodeCount))
+	}
+}
 
-	consulSvcs, err := services.FetchLatestServices(ctx, config)
+func renderServices(svcs []*types.Service, userlists map[string]map[string]string, config *configuration.Config) (string, error) {
+	backendsMap := services.BuildBackends(svcs, config)
+	rulesMap := services.BuildRules(svcs, config)
+
+	renderedConfiguration, err := haproxy.BuildTemplateFile(backendsMap, rulesMap, config)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+
+	// Userlists are top-level sections, so they can follow the template.
+	if len(userlists) > 0 {
+		renderedConfiguration += "\n" + services.BuildUserlists(userlists)
 	}
 
-	if len(consulSvcs) == 0 {
-		glog.V(100).Infoln("Consul returned an empty list of services, no backends will be routed!")
+	return renderedConfiguration, nil
+}
+
+// RenderHAProxyConfiguration renders the HAProxy configuration from
+// a snapshot of service instances, without writing it to the output
+// file or touching the state of the Daemon.
+func RenderHAProxyConfiguration(instances map[string][]*types.ServiceInstance, config *configuration.Config) (string, []*services.ParseError, error) {
+	svcs, parseErrors := services.ParseServices(instances, config)
+
+	// Rendering works offline, Consul KV is never read.
+	userlists, unavailable := loadUserlists(context.Background(), false, config)
+	svcs, parseErrors = services.RejectUnavailableUserlists(svcs, parseErrors, unavailable)
+
+	if config.HAProxy.RuntimeAPI.Enabled {
+		services.AssignSlots(nil, svcs, config.HAProxy.RuntimeAPI.Slots)
 	}
 
-	svcs, err := services.ParseServicesFromConsul(consulSvcs, config)
+	renderedConfiguration, err := renderServices(svcs, userlists, config)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-	backendsMap := services.BuildBackends(svcs, config)
-	rulesMap := services.BuildRules(svcs, config)
+	return renderedConfiguration, parseErrors, nil
+}
+
+// UpdateHAProxyConfigurationFile updates the HAProxy configuration file
+// from the provider and returns the current services and rendered configuration.
+func UpdateHAProxyConfigurationFile(ctx context.Context, provider providers.Provider, config *configuration.Config) ([]*types.Service, string, error) {
+	glog.V(100).Infof("Trying to update HAProxy configuration from %s.", provider.Name())
 
-	parsedFile, err := haproxy.BuildTemplateFile(backendsMap, rulesMap, config)
+	instances, err := provider.FetchServices(ctx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	glog.V(100).Infof("Writing parsed HAProxy configuration file to %s", config.OutputFilePath)
+	if len(instances) == 0 {
+		glog.V(100).Infof("%s returned an empty list of services, no backends will be routed!", provider.Name())
+	}
 
-	outputFile, err := os.OpenFile(config.OutputFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
-		return nil, err
+	svcs, parseErrors := services.ParseServices(instances, config)
+
+	// Replaying works offline like rendering, Consul KV is never read.
+	userlists, unavailable := loadUserlists(ctx, !providers.IsSnapshotProvider(provider), config)
+	svcs, parseErrors = services.RejectUnavailableUserlists(svcs, parseErrors, unavailable)
+
+	for _, parseError := range parseErrors {
+		if parseError.Warning {
+			glog.Warningf("Label warning: %v", parseError)
+
+			metrics.ParseWarnings.WithLabelValues(parseError.ServiceName).Inc()
+
+			continue
+		}
+
+		glog.Errorf("Skipping service with invalid labels: %v", parseError)
+
+		metrics.ParseFailures.WithLabelValues(parseError.ServiceName).Inc()
+	}
+
+	recordDiscoveredServices(svcs, config)
+
+	setParseErrors(parseErrors)
+
+	metrics.LabelDriftServices.Set(float64(len(LabelDrifts())))
+
+	if config.HAProxy.RuntimeAPI.Enabled {
+		services.AssignSlots(gLastServicesList, svcs, config.HAProxy.RuntimeAPI.Slots)
+	}
+
+	renderStart := time.Now()
+
+	parsedFile, err := renderServices(svcs, userlists, config)
+
+	if config.Recording.Directory != "" {
+		if recordErr := recordSnapshot(instances, parsedFile, config); recordErr != nil {
+			glog.Errorf("Got error when recording snapshot: %v", recordErr)
+		}
 	}
-	defer outputFile.Close()
 
-	_, err = outputFile.WriteString(parsedFile)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	metrics.RenderDuration.Observe(time.Since(renderStart).Seconds())
+	metrics.ConfigSize.Set(float64(len(parsedFile)))
+
+	// Access list files must exist before the configuration is validated.
+	if err = haproxy.WriteACLFiles(services.BuildACLFiles(svcs, config), config); err != nil {
+		return nil, "", err
 	}
 
-	return svcs, nil
+	if err = haproxy.WriteConfigurationFile(parsedFile, config); err != nil {
+		return nil, "", err
+	}
+
+	setCurrentState(svcs, parsedFile)
+
+	return svcs, parsedFile, nil
 }
@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+	diffMaxEdits     = 1000
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// diffLines computes the shortest edit script between a and b
+// with the Myers algorithm.
+//
+// It returns false if more than diffMaxEdits edits are required.
+func diffLines(a, b []string) ([]diffOp, bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	middle, ok := myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if !ok {
+		return nil, false
+	}
+
+	ops := make([]diffOp, 0, prefix+len(middle)+suffix)
+
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	ops = append(ops, middle...)
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	return ops, true
+}
+
+func myersDiff(a, b []string) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	maxEdits := min(n+m, diffMaxEdits)
+
+	offset := maxEdits + 1
+	v := make([]int, 2*maxEdits+3)
+
+	// trace[d] holds v[-d..d] as it was before step d.
+	var trace [][]int
+
+	for d := 0; d <= maxEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func backtrackDiff(a, b []string, trace [][]int) []diffOp {
+	var reversed []diffOp
+
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		if d == 0 {
+			for x > 0 && y > 0 {
+				reversed = append(reversed, diffOp{' ', a[x-1]})
+				x--
+				y--
+			}
+
+			break
+		}
+
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			reversed = append(reversed, diffOp{'+', b[prevY]})
+		} else {
+			reversed = append(reversed, diffOp{'-', a[prevX]})
+		}
+
+		x, y = prevX, prevY
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+
+	return ops
+}
+
+// splitLines splits a text into lines, an empty text has no lines
+// and the final newline does not start another line.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// hunkRange renders the start and length of a hunk, a hunk
+// without lines starts at the line before it like diff -u.
+func hunkRange(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, end-start)
+}
+
+// unifiedDiff renders a unified diff between two texts, it
+// returns an empty string if they are equal.
+func unifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+
+	ops, ok := diffLines(splitLines(from), splitLines(to))
+	if !ok {
+		return fmt.Sprintf("--- %s\n+++ %s\n(diff too large, more than %d lines changed)\n", fromName, toName, diffMaxEdits)
+	}
+
+	var result strings.Builder
+
+	fmt.Fprintf(&result, "--- %s\n+++ %s\n", fromName, toName)
+
+	// fromLines[i] and toLines[i] are the line numbers before ops[i].
+	fromLines := make([]int, len(ops)+1)
+	toLines := make([]int, len(ops)+1)
+
+	for i, op := range ops {
+		fromLines[i+1], toLines[i+1] = fromLines[i], toLines[i]
+
+		if op.kind != '+' {
+			fromLines[i+1]++
+		}
+
+		if op.kind != '-' {
+			toLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+
+			continue
+		}
+
+		start := max(i-diffContextLines, 0)
+		end := i
+
+		// Extend the hunk while changes are close enough to share context.
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContextLines {
+				break
+			}
+		}
+
+		end = min(end+diffContextLines, len(ops))
+
+		fmt.Fprintf(&result, "@@ -%s +%s @@\n", hunkRange(fromLines[start], fromLines[end]), hunkRange(toLines[start], toLines[end]))
+
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&result, "%c%s\n", op.kind, op.line)
+		}
+
+		i = end
+	}
+
+	return result.String()
+}
@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines renders the lines l1 to ln, with the given
+// lines replaced, as a text ending with a newline.
+func numberedLines(n int, replaced map[int]string) string {
+	var result strings.Builder
+
+	for i := 1; i <= n; i++ {
+		line, ok := replaced[i]
+		if !ok {
+			line = fmt.Sprintf("l%d", i)
+		}
+
+		result.WriteString(line + "\n")
+	}
+
+	return result.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "equal",
+			from:     numberedLines(3, nil),
+			to:       numberedLines(3, nil),
+			expected: "",
+		},
+		{
+			name:     "empty",
+			from:     "",
+			to:       "",
+			expected: "",
+		},
+		{
+			name: "empty from",
+			from: "",
+			to:   "a\nb\n",
+			expected: "--- previous\n+++ current\n" +
+				"@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "empty to",
+			from: "a\n",
+			to:   "",
+			expected: "--- previous\n+++ current\n" +
+				"@@ -1,1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "insert only",
+			from: numberedLines(8, nil),
+			to:   strings.Replace(numberedLines(8, nil), "l4\n", "l4\nx\n", 1),
+			expected: "--- previous\n+++ current\n" +
+				"@@ -2,6 +2,7 @@\n l2\n l3\n l4\n+x\n l5\n l6\n l7\n",
+		},
+		{
+			name: "delete only",
+			from: numberedLines(8, nil),
+			to:   strings.Replace(numberedLines(8, nil), "l4\n", "", 1),
+			expected: "--- previous\n+++ current\n" +
+				"@@ -1,7 +1,6 @@\n l1\n l2\n l3\n-l4\n l5\n l6\n l7\n",
+		},
+		{
+			name: "change at the start",
+			from: numberedLines(5, nil),
+			to:   numberedLines(5, map[int]string{1: "x"}),
+			expected: "--- previous\n+++ current\n" +
+				"@@ -1,4 +1,4 @@\n-l1\n+x\n l2\n l3\n l4\n",
+		},
+		{
+			name: "change at the end",
+			from: numberedLines(5, nil),
+			to:   numberedLines(5, map[int]string{5: "x"}),
+			expected: "--- previous\n+++ current\n" +
+				"@@ -2,4 +2,4 @@\n l2\n l3\n l4\n-l5\n+x\n",
+		},
+		{
+			name: "hunks merged when sharing context",
+			from: numberedLines(20, nil),
+			to:   numberedLines(20, map[int]string{5: "x", 12: "y"}),
+			expected: "--- previous\n+++ current\n" +
+				"@@ -2,14 +2,14 @@\n l2\n l3\n l4\n-l5\n+x\n l6\n l7\n l8\n l9\n l10\n l11\n-l12\n+y\n l13\n l14\n l15\n",
+		},
+		{
+			name: "hunks split when too far apart",
+			from: numberedLines(20, nil),
+			to:   numberedLines(20, map[int]string{5: "x", 13: "y"}),
+			expected: "--- previous\n+++ current\n" +
+				"@@ -2,7 +2,7 @@\n l2\n l3\n l4\n-l5\n+x\n l6\n l7\n l8\n" +
+				"@@ -10,7 +10,7 @@\n l10\n l11\n l12\n-l13\n+y\n l14\n l15\n l16\n",
+		},
+		{
+			name: "too large",
+			from: strings.Repeat("a\n", diffMaxEdits),
+			to:   strings.Repeat("b\n", diffMaxEdits),
+			expected: "--- previous\n+++ current\n" +
+				fmt.Sprintf("(diff too large, more than %d lines changed)\n", diffMaxEdits),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := unifiedDiff("previous", "current", test.from, test.to); diff != test.expected {
+				t.Errorf("Got diff:\n%s\nexpected:\n%s", diff, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/providers"
+	"github.rbx.com/roblox/roblox-load-balancer/services"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+const recordedConfigurationFileName = "haproxy.cfg"
+
+// snapshotDirectoryLayout is the time layout of the names of snapshot
+// directories, which sort lexically from the oldest to the newest.
+const snapshotDirectoryLayout = "20060102T150405.000000000Z"
+
+// recordSnapshot writes the snapshot of fetched service instances and the configuration
+// rendered from it to a new timestamped directory, then removes the oldest
+// snapshots. The rendered configuration is omitted if rendering failed, and
+// recorded without the password hashes of its userlists.
+func recordSnapshot(instances map[string][]*types.ServiceInstance, renderedConfiguration string, config *configuration.Config) error {
+	snapshotDirectory := filepath.Join(config.Recording.Directory, time.Now().UTC().Format(snapshotDirectoryLayout))
+
+	if err := os.MkdirAll(snapshotDirectory, 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(instances, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(filepath.Join(snapshotDirectory, providers.SnapshotFileName), content, 0644); err != nil {
+		return err
+	}
+
+	if renderedConfiguration != "" {
+		if err = os.WriteFile(filepath.Join(snapshotDirectory, recordedConfigurationFileName), []byte(services.RedactUserlists(renderedConfiguration)), 0644); err != nil {
+			return err
+		}
+	}
+
+	glog.V(100).Infof("Recorded snapshot to %s", snapshotDirectory)
+
+	return rotateSnapshots(config)
+}
+
+// rotateSnapshots removes the oldest snapshots beyond MaxSnapshots,
+// anything in the directory that was not recorded is left alone.
+func rotateSnapshots(config *configuration.Config) error {
+	entries, err := os.ReadDir(config.Recording.Directory)
+	if err != nil {
+		return err
+	}
+
+	var snapshotDirectories []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		if _, err = time.ParseInLocation(snapshotDirectoryLayout, entry.Name(), time.UTC); err == nil {
+			snapshotDirectories = append(snapshotDirectories, entry.Name())
+		}
+	}
+
+	slices.Sort(snapshotDirectories)
+
+	for len(snapshotDirectories) > config.Recording.MaxSnapshots {
+		glog.V(100).Infof("Removing old snapshot %s", snapshotDirectories[0])
+
+		if err = os.RemoveAll(filepath.Join(config.Recording.Directory, snapshotDirectories[0])); err != nil {
+			return err
+		}
+
+		snapshotDirectories = snapshotDirectories[1:]
+	}
+
+	return nil
+}
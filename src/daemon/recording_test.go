@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+)
+
+func TestRotateSnapshots(t *testing.T) {
+	directory := t.TempDir()
+
+	names := []string{
+		"20250101T000000.000000000Z",
+		"20250101T000001.000000000Z",
+		"20250101T000002.000000000Z",
+		"backups",
+		"0-not-a-snapshot",
+	}
+
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(directory, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(directory, "README"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &configuration.Config{Recording: &configuration.RecordingConfig{Directory: directory, MaxSnapshots: 1}}
+
+	if err := rotateSnapshots(config); err != nil {
+		t.Fatalf("Got error when rotating snapshots: %v", err)
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining []string
+	for _, entry := range entries {
+		remaining = append(remaining, entry.Name())
+	}
+
+	expected := []string{"0-not-a-snapshot", "20250101T000002.000000000Z", "README", "backups"}
+	if !slices.Equal(remaining, expected) {
+		t.Errorf("Got %v after rotating, expected %v", remaining, expected)
+	}
+}
@@ -2,8 +2,10 @@ package daemon
 
 import (
 	"context"
+	"crypto/sha256"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -11,6 +13,9 @@ import (
 	"github.com/xcdb/syncx"
 	"github.rbx.com/roblox/roblox-load-balancer/configuration"
 	"github.rbx.com/roblox/roblox-load-balancer/haproxy"
+	"github.rbx.com/roblox/roblox-load-balancer/metrics"
+	"github.rbx.com/roblox/roblox-load-balancer/providers"
+	"github.rbx.com/roblox/roblox-load-balancer/services"
 	"github.rbx.com/roblox/roblox-load-balancer/services/types"
 )
 
@@ -22,40 +27,94 @@ var (
 
 	gRefreshSignal     chan os.Signal
 	gRefreshCancelFunc context.CancelFunc
-	gRefreshEvent      *syncx.ManualResetEvent
+	gRefreshEvent      *syncx.ManualResetEvent = syncx.NewManualResetEvent(false)
 
-	gLastServicesList []*types.Service
+	gLastServicesList  []*types.Service
+	gLastConfiguration string
 )
 
-func hashEquals(a, b []*types.Service) bool {
-	if len(a) != len(b) {
-		return false
-	}
+func configurationDigest(renderedConfiguration string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(renderedConfiguration))
+}
+
+// structuralDigest computes the digest of the configuration without
+// its server lines, if it did not change then only nodes changed.
+func structuralDigest(renderedConfiguration string) [sha256.Size]byte {
+	var structure strings.Builder
+
+	for _, line := range strings.Split(renderedConfiguration, "\n") {
+		trimmedLine := strings.TrimSpace(line)
 
-	hashA, hashB := 0, 0
+		if strings.HasPrefix(trimmedLine, "server ") || strings.HasPrefix(trimmedLine, "server-template ") {
+			continue
+		}
 
-	for i := range a {
-		hashA += int(a[i].Hash())
-		hashB += int(b[i].Hash())
+		structure.WriteString(line)
+		structure.WriteByte('\n')
 	}
 
-	return hashA == hashB
+	return configurationDigest(structure.String())
+}
+
+func watchProvider(ctx context.Context, provider providers.Provider) {
+	go provider.Watch(ctx, func() {
+		glog.V(100).Infof("Services of %s changed, signalling configuration refresh...", provider.Name())
+
+		gRefreshEvent.Signal()
+	})
 }
 
-func shouldReloadHAProxy(currentServices []*types.Service) bool {
-	if gLastServicesList == nil {
+func applyConfigurationChanges(currentServices []*types.Service, renderedConfiguration string, config *configuration.Config) {
+	previousServices, previousConfiguration := gLastServicesList, gLastConfiguration
+
+	if previousServices != nil && configurationDigest(previousConfiguration) == configurationDigest(renderedConfiguration) {
+		glog.V(100).Infoln("Got service update but no changes detected, skipping HAProxy reload.")
+
 		gLastServicesList = currentServices
 
-		return true
+		return
 	}
 
-	if hashEquals(gLastServicesList, currentServices) {
-		return false
+	if previousServices != nil {
+		glog.Infof("HAProxy configuration changed:\n%s", unifiedDiff("previous", "current", services.RedactUserlists(previousConfiguration), services.RedactUserlists(renderedConfiguration)))
 	}
 
-	gLastServicesList = currentServices
+	gLastServicesList, gLastConfiguration = currentServices, renderedConfiguration
+
+	if previousServices != nil && config.HAProxy.RuntimeAPI.Enabled && structuralDigest(previousConfiguration) == structuralDigest(renderedConfiguration) {
+		if commands, ok := services.BuildRuntimeCommands(previousServices, currentServices, config); ok {
+			glog.Infof("Applying %d server changes through the HAProxy Runtime API.", len(commands))
+
+			err := haproxy.ExecuteRuntimeCommands(commands, config)
+			if err == nil {
+				metrics.RuntimeUpdates.Inc()
+
+				if err = haproxy.SaveLastKnownGood(config); err != nil {
+					glog.Warningf("Got error when saving last known good configuration: %v", err)
+				}
 
-	return true
+				return
+			}
+
+			glog.Errorf("Got error when applying server changes through the HAProxy Runtime API, falling back to a reload: %v", err)
+		}
+	}
+
+	glog.Infoln("Reloading HAProxy because of configuration changes.")
+
+	err := haproxy.ReloadHAProxy(config)
+	if err != nil {
+		glog.Errorf("Got error when reloading HAProxy: %v", err)
+
+		// Force a reload on the next refresh.
+		gLastServicesList, gLastConfiguration = nil, ""
+	}
+}
+
+// RequestRefresh wakes up the daemon thread
+// to refresh the configuration immediately.
+func RequestRefresh() {
+	gRefreshEvent.Signal()
 }
 
 // HandleRemoteRefreshRequest handles any SIGUSR1 commands.
@@ -75,7 +134,7 @@ func HandleRemoteRefreshRequest() {
 		}
 
 		glog.Infoln("Received a SIGUSR1, doing manual configuration reload...")
-		gRefreshEvent.Signal()
+		RequestRefresh()
 
 		signal.Stop(gRefreshSignal)
 	}
@@ -83,18 +142,18 @@ func HandleRemoteRefreshRequest() {
 	gDaemonCloseSignalWait.Done()
 }
 
-// Run starts the main Daemon process.
-func Run(config *configuration.Config) {
+// Run starts the main Daemon process with the services of the provider.
+func Run(provider providers.Provider, config *configuration.Config) {
 	gDaemonOnceFlag.Do(func() {
 		glog.Infoln("Starting daemon thread!")
 
-		gRefreshEvent = syncx.NewManualResetEvent(false)
-
 		gDaemonCloseSignalWait.Add(1)
 		ctx, cancel := context.WithCancel(context.Background())
 
 		gContextCancelFunc = cancel
 
+		watchProvider(ctx, provider)
+
 	daemon_loop:
 		for {
 			select {
@@ -104,23 +163,14 @@ func Run(config *configuration.Config) {
 				timeoutContext, cancel := context.WithTimeout(context.Background(), *config.RefreshInterval)
 				gRefreshCancelFunc = cancel
 
-				services, err := UpdateHAProxyConfigurationFile(ctx, config)
+				svcs, renderedConfiguration, err := UpdateHAProxyConfigurationFile(ctx, provider, config)
 				if err != nil {
 					glog.Errorf("Got error when updating HAProxy configuration file: %v", err)
 
 					goto refresh_wait
 				}
 
-				if shouldReloadHAProxy(services) {
-					glog.Infoln("Reloading HAProxy because of service changes.")
-
-					err = haproxy.ReloadHAProxy(config)
-					if err != nil {
-						glog.Errorf("Got error when reloading HAProxy: %v", err)
-					}
-				} else {
-					glog.V(100).Infoln("Got service update but no changes detected, skipping HAProxy reload.")
-				}
+				applyConfigurationChanges(svcs, renderedConfiguration, config)
 
 			refresh_wait:
 
@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.rbx.com/roblox/roblox-load-balancer/services"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+var (
+	gStateLock sync.RWMutex
+
+	gCurrentServices      []*types.Service
+	gCurrentConfiguration string
+	gParseErrors          []*services.ParseError
+)
+
+func setParseErrors(parseErrors []*services.ParseError) {
+	gStateLock.Lock()
+	defer gStateLock.Unlock()
+
+	gParseErrors = parseErrors
+}
+
+func setCurrentState(svcs []*types.Service, renderedConfiguration string) {
+	gStateLock.Lock()
+	defer gStateLock.Unlock()
+
+	gCurrentServices = svcs
+	gCurrentConfiguration = services.RedactUserlists(renderedConfiguration)
+}
+
+// ParseErrors returns the per-service errors and warnings
+// of the last configuration update.
+func ParseErrors() []*services.ParseError {
+	gStateLock.RLock()
+	defer gStateLock.RUnlock()
+
+	return gParseErrors
+}
+
+// LabelDrifts returns the errors and warnings of the services whose
+// instances disagree on their labels, as of the last configuration update.
+func LabelDrifts() []*services.ParseError {
+	gStateLock.RLock()
+	defer gStateLock.RUnlock()
+
+	var drifts []*services.ParseError
+	for _, parseError := range gParseErrors {
+		if parseError.Drift != nil {
+			drifts = append(drifts, parseError)
+		}
+	}
+
+	return drifts
+}
+
+// CurrentServices returns the services of the
+// last successful configuration update.
+func CurrentServices() []*types.Service {
+	gStateLock.RLock()
+	defer gStateLock.RUnlock()
+
+	return gCurrentServices
+}
+
+// CurrentConfiguration returns the HAProxy configuration rendered by
+// the last successful configuration update, without password hashes.
+func CurrentConfiguration() string {
+	gStateLock.RLock()
+	defer gStateLock.RUnlock()
+
+	return gCurrentConfiguration
+}
+
+// Ready determines if a configuration was
+// successfully rendered at least once.
+func Ready() bool {
+	gStateLock.RLock()
+	defer gStateLock.RUnlock()
+
+	return gCurrentServices != nil
+}
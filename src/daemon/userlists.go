@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	capi "github.com/hashicorp/consul/api"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/consul"
+)
+
+// gLastUserlists are the users of every userlist as last loaded,
+// used when a userlist fails to load so that credentials never
+// disappear because of a transient error.
+var gLastUserlists = make(map[string]map[string]string)
+
+// addUser adds a user to a userlist, users that HAProxy cannot
+// check against are skipped.
+func addUser(users map[string]string, name, hash, source string) {
+	if name == "" || hash == "" || strings.ContainsAny(name, " \t") || strings.ContainsAny(hash, " \t") {
+		glog.Warningf("Skipping invalid user %q from %s.", name, source)
+
+		return
+	}
+
+	// Apache specific hashes are not supported by crypt(3).
+	if strings.HasPrefix(hash, "$apr1$") || strings.HasPrefix(hash, "{SHA}") {
+		glog.Warningf("Skipping user %s from %s, its password hash is not supported by crypt(3).", name, source)
+
+		return
+	}
+
+	users[name] = hash
+}
+
+// loadHtpasswdFile loads the users of an htpasswd file.
+func loadHtpasswdFile(fileName string, users map[string]string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, hash, _ := strings.Cut(line, ":")
+		addUser(users, name, hash, fileName)
+	}
+
+	return scanner.Err()
+}
+
+// loadConsulUsers loads the users under a Consul KV prefix.
+func loadConsulUsers(ctx context.Context, prefix string, users map[string]string, config *configuration.Config) error {
+	if consul.GetClient() == nil {
+		if err := consul.InitializeConsul(config); err != nil {
+			return err
+		}
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+
+	options := capi.QueryOptions{}
+
+	pairs, _, err := consul.GetClient().KV().List(prefix, options.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		name := strings.TrimPrefix(pair.Key, prefix)
+
+		// Skip folders.
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		addUser(users, name, strings.TrimSpace(string(pair.Value)), "Consul KV "+prefix)
+	}
+
+	return nil
+}
+
+func loadUserlist(ctx context.Context, userlist *configuration.UserlistConfig, consulKV bool, config *configuration.Config) (map[string]string, error) {
+	users := make(map[string]string)
+
+	for name, hash := range userlist.Users {
+		addUser(users, name, hash, "the configuration")
+	}
+
+	if userlist.File != "" {
+		if err := loadHtpasswdFile(userlist.File, users); err != nil {
+			return nil, fmt.Errorf("htpasswd file %s: %w", userlist.File, err)
+		}
+	}
+
+	if userlist.ConsulKeyPrefix != "" && consulKV {
+		if err := loadConsulUsers(ctx, userlist.ConsulKeyPrefix, users, config); err != nil {
+			return nil, fmt.Errorf("Consul KV prefix %s: %w", userlist.ConsulKeyPrefix, err)
+		}
+	}
+
+	return users, nil
+}
+
+// loadUserlists loads the users of every configured userlist. A userlist
+// that fails to load keeps the users it was last loaded with, it is only
+// unavailable if it was never loaded, the error of every unavailable
+// userlist is returned by name.
+//
+// Consul KV prefixes are skipped unless consulKV is set.
+func loadUserlists(ctx context.Context, consulKV bool, config *configuration.Config) (map[string]map[string]string, map[string]error) {
+	userlists := make(map[string]map[string]string, len(config.Userlists))
+	unavailable := make(map[string]error)
+
+	for name, userlist := range config.Userlists {
+		users, err := loadUserlist(ctx, userlist, consulKV, config)
+		if err != nil {
+			lastUsers, ok := gLastUserlists[name]
+			if !ok {
+				unavailable[name] = err
+
+				continue
+			}
+
+			glog.Errorf("Got error when loading userlist %s, keeping its previous users: %v", name, err)
+
+			users = lastUsers
+		}
+
+		userlists[name] = users
+	}
+
+	maps.Copy(gLastUserlists, userlists)
+
+	return userlists, unavailable
+}
@@ -11,8 +11,16 @@ var (
 
 	// DryRun reads from Consul, builds the config, and outputs to the file without starting the Daemon or reloading HAProxy.
 	DryRun = flag.Bool("dry-run", false, "Reads from Consul, builds the config, and outputs to the file without starting the Daemon or reloading HAProxy.")
+
+	// ReplaySnapshotPath is the path to a recorded snapshot to use instead of the configured providers.
+	ReplaySnapshotPath = flag.String("replay-snapshot-path", "", "The path to a recorded snapshot file or directory to use instead of the configured providers.")
 )
 
+// RenderCommand renders the configuration from a snapshot of services
+// to STDOUT, without connecting to any provider or starting the Daemon.
+const RenderCommand string = "render"
+
 const FlagsUsageString string = `
 	[-h|--help]
-	[--configuration-file-path[=]] [--dry-run]`
+	[--configuration-file-path[=]] [--dry-run] [--replay-snapshot-path[=]]
+	[render <snapshot-file-path>]`
@@ -0,0 +1,152 @@
+package haproxy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+)
+
+// writeFileAtomic writes the content to a temporary file next to filePath,
+// optionally validates it, and then renames it into place.
+func writeFileAtomic(filePath string, content []byte, validate func(string) error) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tempFilePath := tempFile.Name()
+	defer os.Remove(tempFilePath) // No-op once renamed.
+
+	if _, err = tempFile.Write(content); err != nil {
+		tempFile.Close()
+
+		return err
+	}
+
+	if err = tempFile.Sync(); err != nil {
+		tempFile.Close()
+
+		return err
+	}
+
+	if err = tempFile.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tempFilePath, 0644); err != nil {
+		return err
+	}
+
+	if validate != nil {
+		if err = validate(tempFilePath); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tempFilePath, filePath)
+}
+
+// WriteConfigurationFile writes the configuration to a temporary file,
+// validates it and only then atomically replaces the output file.
+//
+// If validation fails the output file is left untouched.
+func WriteConfigurationFile(content string, config *configuration.Config) error {
+	glog.V(100).Infof("Writing parsed HAProxy configuration file to %s", config.OutputFilePath)
+
+	return writeFileAtomic(config.OutputFilePath, []byte(content), func(filePath string) error {
+		return validateHAProxyConfigurationFile(config, filePath)
+	})
+}
+
+// SaveLastKnownGood copies the current output file
+// to the last known good file.
+func SaveLastKnownGood(config *configuration.Config) error {
+	content, err := os.ReadFile(config.OutputFilePath)
+	if err != nil {
+		return err
+	}
+
+	return saveLastKnownGood(content, config)
+}
+
+func saveLastKnownGood(content []byte, config *configuration.Config) error {
+	glog.V(100).Infof("Saving last known good HAProxy configuration to %s", config.HAProxy.LastKnownGoodFilePath)
+
+	return writeFileAtomic(config.HAProxy.LastKnownGoodFilePath, content, nil)
+}
+
+// RestoreLastKnownGood replaces the output file
+// with the last known good file.
+func RestoreLastKnownGood(config *configuration.Config) error {
+	content, err := os.ReadFile(config.HAProxy.LastKnownGoodFilePath)
+	if err != nil {
+		return err
+	}
+
+	glog.Warningf("Restoring last known good HAProxy configuration from %s", config.HAProxy.LastKnownGoodFilePath)
+
+	return writeFileAtomic(config.OutputFilePath, content, func(filePath string) error {
+		return validateHAProxyConfigurationFile(config, filePath)
+	})
+}
+
+// WriteACLFiles writes the files of large access lists, see
+// services.BuildACLFiles, and removes the files that are used by
+// neither the current nor the last known good configuration.
+//
+// Files are named after their content, so existing files are kept as is.
+func WriteACLFiles(files map[string]string, config *configuration.Config) error {
+	if err := os.MkdirAll(config.AccessLists.Directory, 0755); err != nil {
+		return err
+	}
+
+	for filePath, content := range files {
+		if _, err := os.Stat(filePath); err == nil {
+			continue
+		}
+
+		glog.V(100).Infof("Writing access list file %s", filePath)
+
+		if err := writeFileAtomic(filePath, []byte(content), nil); err != nil {
+			return err
+		}
+	}
+
+	return pruneACLFiles(files, config)
+}
+
+func pruneACLFiles(files map[string]string, config *configuration.Config) error {
+	entries, err := os.ReadDir(config.AccessLists.Directory)
+	if err != nil {
+		return err
+	}
+
+	// The last known good configuration may be restored, so its files are kept.
+	lastKnownGood, err := os.ReadFile(config.HAProxy.LastKnownGoodFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, entry := range entries {
+		filePath := filepath.Join(config.AccessLists.Directory, entry.Name())
+
+		if entry.IsDir() || filepath.Ext(filePath) != ".acl" {
+			continue
+		}
+
+		if _, ok := files[filePath]; ok || bytes.Contains(lastKnownGood, []byte(filePath)) {
+			continue
+		}
+
+		glog.V(100).Infof("Removing unused access list file %s", filePath)
+
+		if err = os.Remove(filePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
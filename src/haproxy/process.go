@@ -6,16 +6,23 @@ import (
 	"os/exec"
 	"path"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/golang/glog"
 	ps "github.com/mitchellh/go-ps"
 	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/metrics"
 )
 
 var (
 	gHAProxyProcess *os.Process
 
+	// gHAProxyStarted determines if HAProxy was started or recovered
+	// before, so that any later start is counted as a restart.
+	gHAProxyStarted atomic.Bool
+
 	gHAProxyStdoutFile *os.File
 	gHAProxyStderrFile *os.File
 )
@@ -44,6 +51,7 @@ func recoverHAProxyProcess(config *configuration.Config) error {
 			glog.Infof("Found existing HAProxy process with PID %d", proc.Pid())
 
 			gHAProxyProcess, _ = os.FindProcess(proc.Pid())
+			gHAProxyStarted.Store(true)
 
 			return nil
 		}
@@ -93,12 +101,28 @@ func InitializeHAProxy(config *configuration.Config) error {
 	return nil
 }
 
+// startGracePeriod is how long a new HAProxy process must run before
+// its configuration is saved as the last known good configuration.
+const startGracePeriod = 5 * time.Second
+
 func startHAProxy(config *configuration.Config) {
 	glog.Infof("Starting HAProxy as: %s %s (max attempts: %d)", config.HAProxy.Path, strings.Join(config.HAProxy.Args, " "), *config.HAProxy.MaxStartAttempts)
 
 	for i := 0; i < *config.HAProxy.MaxStartAttempts; i++ {
 		glog.Infof("Starting HAProxy (attempt %d/%d)...", i+1, *config.HAProxy.MaxStartAttempts)
 
+		// Starts after a crash come from the next reload, count them too.
+		if gHAProxyStarted.Swap(true) {
+			metrics.HAProxyRestarts.Inc()
+		}
+
+		content, err := os.ReadFile(config.OutputFilePath)
+		if err != nil {
+			glog.Errorf("Got error when reading HAProxy configuration file: %v", err)
+
+			continue
+		}
+
 		cmd := exec.Command(config.HAProxy.Path)
 		cmd.Args = append(cmd.Args, config.HAProxy.Args...)
 		cmd.Env = config.HAProxy.Env
@@ -106,7 +130,7 @@ func startHAProxy(config *configuration.Config) {
 		cmd.Stdout = gHAProxyStdoutFile
 		cmd.Stderr = gHAProxyStderrFile
 
-		if err := cmd.Start(); err != nil {
+		if err = cmd.Start(); err != nil {
 			glog.Errorf("Got error when starting HAProxy: %v", err)
 
 			continue
@@ -116,11 +140,31 @@ func startHAProxy(config *configuration.Config) {
 
 		glog.Infof("Started HAProxy with PID %d, waiting for exit...", gHAProxyProcess.Pid)
 
-		state, _ := cmd.Process.Wait() // Don't care about error here as it will most likely just be because it was killed from TeardownHAProxy.
+		exited := make(chan *os.ProcessState, 1)
+		go func() {
+			state, _ := cmd.Process.Wait() // Don't care about error here as it will most likely just be because it was killed from TeardownHAProxy.
+			exited <- state
+		}()
+
+		var state *os.ProcessState
+
+		select {
+		case state = <-exited:
+		case <-time.After(startGracePeriod):
+			if err = saveLastKnownGood(content, config); err != nil {
+				glog.Warningf("Got error when saving last known good configuration: %v", err)
+			}
+
+			state = <-exited
+		}
 
 		// Code 0 (normal exit) and 130 (SIGINT) are acceptable.
 		if state.ExitCode() != 0 && state.ExitCode() != 130 && state.ExitCode() != -1 {
-			glog.Errorf("HAProxy process exited with non-zero exit code %d", state.ExitCode())
+			glog.Errorf("HAProxy process exited with non-zero exit code %d, restoring last known good configuration", state.ExitCode())
+
+			if err = RestoreLastKnownGood(config); err != nil {
+				glog.Errorf("Got error when restoring last known good configuration: %v", err)
+			}
 
 			continue
 		}
@@ -128,15 +172,25 @@ func startHAProxy(config *configuration.Config) {
 		return
 	}
 
-	glog.Errorf("Exceeded maximum HAProxy start attempts (%d), giving up!", config.HAProxy.MaxStartAttempts)
+	glog.Errorf("Exceeded maximum HAProxy start attempts (%d), giving up!", *config.HAProxy.MaxStartAttempts)
 }
 
 // ReloadHAProxy reloads the HAProxy configuration
 // file.
 //
 // If the config file doesn't exist it exits immediately with no error.
-// If the process is running it will send a SIGHUP to it.
+// If the process is running it will reload it, through the master CLI
+// if configured, or else with a SIGHUP.
 // Otherwise it will create the process.
+//
+// On success the config file is saved as the last known good
+// configuration, on failure the last known good configuration is
+// restored and reloaded, and the original error is returned.
+//
+// A reload with a SIGHUP happens in the background so its failure is
+// not detected, only the master CLI of HAProxy 2.7 and later reports it.
+// A new process that exits with an error within startGracePeriod
+// is started again with the last known good configuration.
 func ReloadHAProxy(config *configuration.Config) error {
 	glog.Infoln("Reloading HAProxy!")
 
@@ -146,19 +200,63 @@ func ReloadHAProxy(config *configuration.Config) error {
 		return nil // Take the case of initial config as not exist.
 	}
 
+	metrics.Reloads.Inc()
+
+	started, err := reloadHAProxy(config)
+	if err != nil {
+		metrics.ReloadFailures.Inc()
+
+		glog.Errorf("Got error when reloading HAProxy, rolling back to last known good configuration: %v", err)
+
+		if restoreErr := RestoreLastKnownGood(config); restoreErr != nil {
+			glog.Errorf("Got error when restoring last known good configuration: %v", restoreErr)
+
+			return err
+		}
+
+		if _, reloadErr := reloadHAProxy(config); reloadErr != nil {
+			glog.Errorf("Got error when reloading last known good configuration: %v", reloadErr)
+		}
+
+		return err // The new configuration is still not live.
+	}
+
+	metrics.SetLastSuccessfulReload()
+
+	// A new process saves its configuration once it keeps running.
+	if started {
+		return nil
+	}
+
+	if err = SaveLastKnownGood(config); err != nil {
+		glog.Warningf("Got error when saving last known good configuration: %v", err)
+	}
+
+	return nil
+}
+
+// reloadHAProxy validates and reloads the configuration,
+// it reports if a new process was started instead.
+func reloadHAProxy(config *configuration.Config) (bool, error) {
 	if err := validateHAProxyConfiguration(config); err != nil {
-		return err
+		return false, err
 	}
 
 	if haproxyRunning() {
+		if config.HAProxy.RuntimeAPI.MasterSocket {
+			glog.Infof("Reloading HAProxy through the master CLI on %s...", config.HAProxy.RuntimeAPI.SocketAddress)
+
+			return false, reloadThroughMasterCLI(config)
+		}
+
 		glog.Infof("Sending SIGHUP to HAProxy process %d...", gHAProxyProcess.Pid)
 
-		return gHAProxyProcess.Signal(syscall.SIGHUP)
+		return false, gHAProxyProcess.Signal(syscall.SIGHUP)
 	}
 
 	go startHAProxy(config)
 
-	return nil
+	return true, nil
 }
 
 // TeardownHAProxy kills the current HAProxy process.
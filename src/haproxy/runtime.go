@@ -0,0 +1,107 @@
+package haproxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+)
+
+// runtimeErrorPrefixes are the prefixes of Runtime API
+// responses that indicate a command failed.
+var runtimeErrorPrefixes = []string{
+	"No such",
+	"Unknown command",
+	"Permission denied",
+	"Require ",
+	"Invalid",
+	"Can't",
+	"Backend is using a static",
+}
+
+// sendSocketCommand sends a single command to the socket
+// of SocketAddress and reads the whole response.
+func sendSocketCommand(command string, config *configuration.Config) (string, error) {
+	network := "tcp"
+	if filepath.IsAbs(config.HAProxy.RuntimeAPI.SocketAddress) {
+		network = "unix"
+	}
+
+	conn, err := net.DialTimeout(network, config.HAProxy.RuntimeAPI.SocketAddress, config.HAProxy.RuntimeAPI.Timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(config.HAProxy.RuntimeAPI.Timeout)); err != nil {
+		return "", err
+	}
+
+	if _, err = conn.Write([]byte(command + "\n")); err != nil {
+		return "", err
+	}
+
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(response)), nil
+}
+
+func executeRuntimeCommand(command string, config *configuration.Config) (string, error) {
+	if config.HAProxy.RuntimeAPI.MasterSocket {
+		command = "@1 " + command // Forward to the current worker.
+	}
+
+	return sendSocketCommand(command, config)
+}
+
+// reloadThroughMasterCLI reloads HAProxy with the reload command of the
+// master CLI, which from HAProxy 2.7 waits for the new workers and reports
+// with Success=0 and the startup logs when they failed to start.
+//
+// Older versions do not report the status, their reloads are assumed to succeed.
+func reloadThroughMasterCLI(config *configuration.Config) error {
+	response, err := sendSocketCommand("reload", config)
+	if err != nil {
+		return err
+	}
+
+	status, logs, _ := strings.Cut(response, "\n--")
+	if strings.TrimSpace(status) == "Success=0" {
+		return fmt.Errorf("HAProxy failed to reload: %s", strings.TrimSpace(logs))
+	}
+
+	return nil
+}
+
+// ExecuteRuntimeCommands sends the commands to the HAProxy Runtime API
+// one by one, it stops at the first command that fails.
+func ExecuteRuntimeCommands(commands []string, config *configuration.Config) error {
+	for _, command := range commands {
+		glog.V(100).Infof("Sending HAProxy Runtime API command: %s", command)
+
+		response, err := executeRuntimeCommand(command, config)
+		if err != nil {
+			return err
+		}
+
+		for _, prefix := range runtimeErrorPrefixes {
+			if strings.HasPrefix(response, prefix) {
+				return fmt.Errorf("HAProxy Runtime API command %q failed: %s", command, response)
+			}
+		}
+
+		if response != "" {
+			glog.V(100).Infof("HAProxy Runtime API response: %s", response)
+		}
+	}
+
+	return nil
+}
@@ -6,12 +6,17 @@ import (
 	"os/exec"
 
 	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/metrics"
 )
 
 func validateHAProxyConfiguration(config *configuration.Config) error {
-	cmd := exec.Command(config.HAProxy.Path, "-c", "-f", config.OutputFilePath)
-	err := cmd.Start()
+	return validateHAProxyConfigurationFile(config, config.OutputFilePath)
+}
+
+func validateHAProxyConfigurationFile(config *configuration.Config, filePath string) error {
+	cmd := exec.Command(config.HAProxy.Path, "-c", "-f", filePath)
 	cmd.Stderr = os.Stderr
+	err := cmd.Start()
 	if err != nil {
 		return err
 	}
@@ -26,5 +31,7 @@ func validateHAProxyConfiguration(config *configuration.Config) error {
 		return nil
 	}
 
+	metrics.ValidationFailures.Inc()
+
 	return errors.New("invalid HAProxy configuration specified.")
 }
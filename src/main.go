@@ -3,16 +3,18 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/admin"
 	"github.rbx.com/roblox/roblox-load-balancer/configuration"
-	"github.rbx.com/roblox/roblox-load-balancer/consul"
 	"github.rbx.com/roblox/roblox-load-balancer/daemon"
 	"github.rbx.com/roblox/roblox-load-balancer/flags"
 	"github.rbx.com/roblox/roblox-load-balancer/haproxy"
+	"github.rbx.com/roblox/roblox-load-balancer/providers"
 )
 
 var applicationName string
@@ -24,6 +26,35 @@ func init() {
 	flags.SetupFlags(applicationName, buildMode, commitSha)
 }
 
+// render renders the configuration from a snapshot of services to STDOUT.
+func render(config *configuration.Config, snapshotFilePath string) error {
+	if snapshotFilePath == "" {
+		return fmt.Errorf("The snapshot file must be specified!")
+	}
+
+	snapshot, err := providers.LoadSnapshot(snapshotFilePath, config)
+	if err != nil {
+		return err
+	}
+
+	renderedConfiguration, parseErrors, err := daemon.RenderHAProxyConfiguration(snapshot, config)
+	if err != nil {
+		return err
+	}
+
+	for _, parseError := range parseErrors {
+		if parseError.Warning {
+			glog.Warningf("Label warning: %v", parseError)
+		} else {
+			glog.Errorf("Skipping service with invalid labels: %v", parseError)
+		}
+	}
+
+	_, err = fmt.Fprint(os.Stdout, renderedConfiguration)
+
+	return err
+}
+
 // Main entrypoint.
 func main() {
 	if *flags.HelpFlag {
@@ -39,7 +70,27 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := consul.InitializeConsul(config); err != nil {
+	if flag.Arg(0) == flags.RenderCommand {
+		if err := render(config, flag.Arg(1)); err != nil {
+			glog.Error(err)
+
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	var provider providers.Provider
+	if *flags.ReplaySnapshotPath != "" {
+		// Replaying must not record copies of the replayed snapshot.
+		config.Recording.Directory = ""
+
+		provider, err = providers.NewSnapshotProvider(*flags.ReplaySnapshotPath, config)
+	} else {
+		provider, err = providers.New(config)
+	}
+
+	if err != nil {
 		glog.Error(err)
 
 		os.Exit(1)
@@ -48,7 +99,7 @@ func main() {
 	if *flags.DryRun {
 		glog.Infoln("Doing dry-run to load initial configuration...")
 
-		_, err := daemon.UpdateHAProxyConfigurationFile(context.Background(), config)
+		_, _, err := daemon.UpdateHAProxyConfigurationFile(context.Background(), provider, config)
 		if err != nil {
 			glog.Error(err)
 
@@ -64,14 +115,17 @@ func main() {
 		os.Exit(1)
 	}
 
-	go daemon.Run(config)
+	go daemon.Run(provider, config)
 	go daemon.HandleRemoteRefreshRequest()
 
+	admin.Serve(config)
+
 	osSignal := make(chan os.Signal, 1)
 	signal.Notify(osSignal, syscall.SIGABRT, syscall.SIGINT, syscall.SIGTERM)
 	defer func() {
 		sig := <-osSignal
 
+		admin.Shutdown()
 		daemon.Exit()
 
 		haproxy.TeardownHAProxy()
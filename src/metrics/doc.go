@@ -0,0 +1,3 @@
+// Package metrics contains the Prometheus
+// metrics exported by the daemon.
+package metrics
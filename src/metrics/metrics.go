@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "roblox_load_balancer"
+
+var (
+	// ProviderFetchDuration is the duration of fetching services per provider.
+	ProviderFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_fetch_duration_seconds",
+		Help:      "Duration of fetching the latest services per provider.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	// ProviderFetchErrors is the number of failed fetches per provider.
+	ProviderFetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_fetch_errors_total",
+		Help:      "Number of failed fetches of the latest services per provider.",
+	}, []string{"provider"})
+
+	// DiscoveredServices is the number of discovered services per entrypoint.
+	DiscoveredServices = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "discovered_services",
+		Help:      "Number of discovered services per entrypoint.",
+	}, []string{"entrypoint"})
+
+	// DiscoveredNodes is the number of discovered nodes per entrypoint.
+	DiscoveredNodes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "discovered_nodes",
+		Help:      "Number of discovered nodes per entrypoint.",
+	}, []string{"entrypoint"})
+
+	// ParseFailures is the number of label parse failures per service.
+	ParseFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parse_failures_total",
+		Help:      "Number of label parse failures per service.",
+	}, []string{"service"})
+
+	// ParseWarnings is the number of label parse warnings per service.
+	ParseWarnings = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parse_warnings_total",
+		Help:      "Number of label parse warnings per service.",
+	}, []string{"service"})
+
+	// LabelDriftServices is the number of services whose
+	// instances disagree on their labels.
+	LabelDriftServices = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "label_drift_services",
+		Help:      "Number of services whose instances disagree on their labels.",
+	})
+
+	// RenderDuration is the duration of rendering the HAProxy configuration.
+	RenderDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "render_duration_seconds",
+		Help:      "Duration of rendering the HAProxy configuration.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// ConfigSize is the size of the last rendered HAProxy configuration.
+	ConfigSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_size_bytes",
+		Help:      "Size of the last rendered HAProxy configuration.",
+	})
+
+	// ValidationFailures is the number of HAProxy configuration validation failures.
+	ValidationFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_failures_total",
+		Help:      "Number of HAProxy configuration validation failures.",
+	})
+
+	// Reloads is the number of HAProxy reloads.
+	Reloads = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reloads_total",
+		Help:      "Number of HAProxy reloads.",
+	})
+
+	// ReloadFailures is the number of failed HAProxy reloads.
+	ReloadFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reload_failures_total",
+		Help:      "Number of failed HAProxy reloads.",
+	})
+
+	// RuntimeUpdates is the number of server updates applied through the HAProxy Runtime API.
+	RuntimeUpdates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runtime_updates_total",
+		Help:      "Number of server updates applied through the HAProxy Runtime API.",
+	})
+
+	// HAProxyRestarts is the number of times the HAProxy process was restarted.
+	HAProxyRestarts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "haproxy_restarts_total",
+		Help:      "Number of times the HAProxy process was restarted after exiting.",
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "seconds_since_last_successful_reload",
+		Help:      "Seconds since the last successful HAProxy reload, or since startup.",
+	}, func() float64 {
+		return time.Since(time.Unix(0, lastSuccessfulReload.Load())).Seconds()
+	})
+)
+
+var lastSuccessfulReload atomic.Int64
+
+func init() {
+	SetLastSuccessfulReload()
+}
+
+// SetLastSuccessfulReload records a successful HAProxy reload.
+func SetLastSuccessfulReload() {
+	lastSuccessfulReload.Store(time.Now().UnixNano())
+}
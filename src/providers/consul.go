@@ -0,0 +1,327 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	capi "github.com/hashicorp/consul/api"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/consul"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+func fetchServiceNames(ctx context.Context, config *configuration.Config, datacenter string, waitIndex uint64) (map[string][]string, *capi.QueryMeta, error) {
+	options := capi.QueryOptions{
+		Datacenter: datacenter,
+		Filter:     fmt.Sprintf("\"%s.enable=true\" in ServiceTags", config.Prefix),
+		WaitIndex:  waitIndex,
+	}
+
+	return consul.GetClient().Catalog().Services(options.WithContext(ctx))
+}
+
+func healthEntryToCatalogService(entry *capi.ServiceEntry) *capi.CatalogService {
+	return &capi.CatalogService{
+		ID:                       entry.Node.ID,
+		Node:                     entry.Node.Node,
+		Address:                  entry.Node.Address,
+		Datacenter:               entry.Node.Datacenter,
+		TaggedAddresses:          entry.Node.TaggedAddresses,
+		NodeMeta:                 entry.Node.Meta,
+		ServiceID:                entry.Service.ID,
+		ServiceName:              entry.Service.Service,
+		ServiceAddress:           entry.Service.Address,
+		ServiceTaggedAddresses:   entry.Service.TaggedAddresses,
+		ServiceTags:              entry.Service.Tags,
+		ServiceMeta:              entry.Service.Meta,
+		ServicePort:              entry.Service.Port,
+		ServiceWeights:           capi.Weights(entry.Service.Weights),
+		ServiceEnableTagOverride: entry.Service.EnableTagOverride,
+		ServiceProxy:             entry.Service.Proxy,
+		ServiceLocality:          entry.Service.Locality,
+		CreateIndex:              entry.Service.CreateIndex,
+		Checks:                   entry.Checks,
+		ModifyIndex:              entry.Service.ModifyIndex,
+		Namespace:                entry.Service.Namespace,
+		Partition:                entry.Service.Partition,
+	}
+}
+
+func fetchServiceInstances(ctx context.Context, service string, config *configuration.Config, datacenter string, waitIndex uint64) ([]*capi.CatalogService, *capi.QueryMeta, error) {
+	options := capi.QueryOptions{
+		Datacenter: datacenter,
+		WaitIndex:  waitIndex,
+	}
+
+	tag := fmt.Sprintf("%s.enable=true", config.Prefix)
+
+	if config.Consul.HealthMode == configuration.ConsulHealthModeCatalog {
+		return consul.GetClient().Catalog().Service(service, tag, options.WithContext(ctx))
+	}
+
+	// Critical instances are still fetched so they can be rendered as disabled.
+	entries, meta, err := consul.GetClient().Health().Service(service, tag, false, options.WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serviceNodes := make([]*capi.CatalogService, 0, len(entries))
+	for _, entry := range entries {
+		serviceNodes = append(serviceNodes, healthEntryToCatalogService(entry))
+	}
+
+	return serviceNodes, meta, nil
+}
+
+func isInstanceHealthy(entry *capi.CatalogService, config *configuration.Config) bool {
+	switch config.Consul.HealthMode {
+	case configuration.ConsulHealthModePassing:
+		return entry.Checks.AggregatedStatus() == capi.HealthPassing
+	case configuration.ConsulHealthModeWarning:
+		status := entry.Checks.AggregatedStatus()
+
+		return status == capi.HealthPassing || status == capi.HealthWarning
+	default:
+		return true
+	}
+}
+
+// instanceInWarning determines if an instance is routed to while its
+// health checks are in warning, as only the warning health mode does.
+func instanceInWarning(entry *capi.CatalogService, config *configuration.Config) bool {
+	return config.Consul.HealthMode == configuration.ConsulHealthModeWarning && entry.Checks.AggregatedStatus() == capi.HealthWarning
+}
+
+// instanceWeight computes the weight of an instance from the weights of
+// the service, overridden by config.Consul.WeightMetaKey, or the Warning
+// weight of the service if it registers one and the instance is in warning.
+func instanceWeight(entry *capi.CatalogService, config *configuration.Config) *int {
+	weight := entry.ServiceWeights.Passing
+	explicit := weight != 0
+
+	if value, ok := entry.ServiceMeta[config.Consul.WeightMetaKey]; ok {
+		metaWeight, err := strconv.Atoi(value)
+		if err != nil {
+			glog.Warningf("Ignoring invalid weight %q of instance %s of service %s.", value, entry.ServiceID, entry.ServiceName)
+		} else {
+			weight = metaWeight
+			explicit = true
+		}
+	}
+
+	if instanceInWarning(entry, config) && entry.ServiceWeights.Warning != entry.ServiceWeights.Passing {
+		weight = entry.ServiceWeights.Warning
+		explicit = true
+	}
+
+	if !explicit {
+		return nil
+	}
+
+	return &weight
+}
+
+// instanceWeightPercent gets the percentage of its weight an instance in
+// warning keeps, unless the service registers its own Warning weight.
+func instanceWeightPercent(entry *capi.CatalogService, config *configuration.Config) *int {
+	if !instanceInWarning(entry, config) || entry.ServiceWeights.Warning != entry.ServiceWeights.Passing {
+		return nil
+	}
+
+	return config.Consul.WarningWeightPercent
+}
+
+func catalogServiceToInstance(entry *capi.CatalogService, config *configuration.Config) *types.ServiceInstance {
+	instance := &types.ServiceInstance{
+		ID:            entry.ServiceID,
+		ServiceName:   entry.ServiceName,
+		Name:          entry.Node,
+		Address:       entry.Address,
+		Port:          entry.ServicePort,
+		Tags:          entry.ServiceTags,
+		Datacenter:    entry.Datacenter,
+		Meta:          entry.ServiceMeta,
+		Weight:        instanceWeight(entry, config),
+		WeightPercent: instanceWeightPercent(entry, config),
+		ModifyIndex:   entry.ModifyIndex,
+		Unhealthy:     !isInstanceHealthy(entry, config),
+	}
+
+	if externalSource, ok := entry.ServiceMeta["external-source"]; ok && externalSource == "nomad" {
+		// Nomad service IDs are _nomad-task-<ALLOC id>-..., use the short ALLOC id.
+		if parts := strings.Split(entry.ServiceID, "-"); len(parts) > 2 {
+			instance.Name = parts[2]
+		} else {
+			instance.Warning = fmt.Sprintf("Unexpected Nomad service ID %s of instance on node %s, naming the server after the node", entry.ServiceID, entry.Node)
+		}
+	}
+
+	return instance
+}
+
+// CatalogToInstances converts Consul Catalog services
+// to provider-neutral service instances.
+func CatalogToInstances(catalogServices map[string][]*capi.CatalogService, config *configuration.Config) map[string][]*types.ServiceInstance {
+	result := make(map[string][]*types.ServiceInstance, len(catalogServices))
+
+	for serviceName, entries := range catalogServices {
+		instances := make([]*types.ServiceInstance, 0, len(entries))
+		for _, entry := range entries {
+			instances = append(instances, catalogServiceToInstance(entry, config))
+		}
+
+		result[serviceName] = instances
+	}
+
+	return result
+}
+
+// consulProvider discovers services from the Consul Catalog.
+type consulProvider struct {
+	config *configuration.Config
+
+	lock                 sync.Mutex
+	localDatacenter      string
+	preparedQueryDigests map[string][sha256.Size]byte
+}
+
+func newConsulProvider(config *configuration.Config) (*consulProvider, error) {
+	if err := consul.InitializeConsul(config); err != nil {
+		return nil, err
+	}
+
+	return &consulProvider{config: config, localDatacenter: config.Consul.Datacenter}, nil
+}
+
+func (p *consulProvider) Name() string {
+	return configuration.ProviderConsul
+}
+
+// remoteDatacenters gets the configured datacenters other than the
+// local one, which is looked up from the agent if not configured.
+func (p *consulProvider) remoteDatacenters() ([]string, error) {
+	if len(p.config.Consul.Datacenters) == 0 {
+		return nil, nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.localDatacenter == "" {
+		self, err := consul.GetClient().Agent().Self()
+		if err != nil {
+			return nil, fmt.Errorf("Could not get the local datacenter from the Consul agent: %w", err)
+		}
+
+		datacenter, ok := self["Config"]["Datacenter"].(string)
+		if !ok || datacenter == "" {
+			return nil, fmt.Errorf("The Consul agent did not report its datacenter")
+		}
+
+		p.localDatacenter = datacenter
+	}
+
+	var datacenters []string
+	for _, datacenter := range p.config.Consul.Datacenters {
+		if datacenter != p.localDatacenter && !slices.Contains(datacenters, datacenter) {
+			datacenters = append(datacenters, datacenter)
+		}
+	}
+
+	return datacenters, nil
+}
+
+func (p *consulProvider) fetchDatacenterServices(ctx context.Context, datacenter string) (map[string][]*capi.CatalogService, error) {
+	services, _, err := fetchServiceNames(ctx, p.config, datacenter, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]*capi.CatalogService)
+
+	for service := range services {
+		serviceNodes, _, err := fetchServiceInstances(ctx, service, p.config, datacenter, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		result[service] = serviceNodes
+	}
+
+	return result, nil
+}
+
+// FetchServices fetches a map of service name to service instance from Consul,
+// in the local datacenter and every remote datacenter configured.
+// Remote datacenters that fail are skipped.
+// This method does not block, see Watch for change notifications.
+func (p *consulProvider) FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error) {
+	remoteDatacenters, err := p.remoteDatacenters()
+	if err != nil {
+		return nil, err
+	}
+
+	localServices, err := p.fetchDatacenterServices(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := CatalogToInstances(localServices, p.config)
+
+	for _, datacenter := range remoteDatacenters {
+		// An unreachable remote datacenter only loses its backups.
+		remoteServices, err := p.fetchDatacenterServices(ctx, datacenter)
+		if err != nil {
+			glog.Errorf("Got error when fetching services from datacenter %s: %v", datacenter, err)
+
+			continue
+		}
+
+		for serviceName, instances := range CatalogToInstances(remoteServices, p.config) {
+			for _, instance := range instances {
+				instance.Remote = true
+			}
+
+			result[serviceName] = append(result[serviceName], instances...)
+		}
+	}
+
+	p.applyPreparedQueries(ctx, result)
+
+	return result, nil
+}
+
+// Watch long-polls the Consul Catalog with blocking queries,
+// unless disabled in the configuration.
+func (p *consulProvider) Watch(ctx context.Context, notify func()) {
+	if !*p.config.Consul.Watch {
+		return
+	}
+
+	remoteDatacenters, err := p.remoteDatacenters()
+	for err != nil {
+		glog.Errorf("Got error when starting Consul Catalog watch: %v", err)
+
+		if !sleepContext(ctx, *p.config.Consul.WatchRetryInterval) {
+			return
+		}
+
+		remoteDatacenters, err = p.remoteDatacenters()
+	}
+
+	go p.watchPreparedQueries(ctx, notify)
+
+	watcher := newConsulWatcher(p.config, append([]string{""}, remoteDatacenters...))
+
+	go watcher.Run(ctx)
+
+	for range watcher.Events() {
+		notify()
+	}
+}
@@ -0,0 +1,188 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/golang/glog"
+	capi "github.com/hashicorp/consul/api"
+	"github.rbx.com/roblox/roblox-load-balancer/consul"
+	"github.rbx.com/roblox/roblox-load-balancer/services"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// preparedQueryLabel gets the prepared query declared by the
+// be.preparedQuery label of an instance, if any.
+func preparedQueryLabel(instance *types.ServiceInstance, prefix string) string {
+	query, _ := services.LookupInstanceLabel(instance, prefix, "haproxy.be.preparedQuery")
+
+	return query
+}
+
+// instancesDigest computes a digest of the instances, regardless of their order.
+func instancesDigest(instances []*types.ServiceInstance) [sha256.Size]byte {
+	sorted := slices.SortedFunc(slices.Values(instances), func(a, b *types.ServiceInstance) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	content, _ := json.Marshal(sorted)
+
+	return sha256.Sum256(content)
+}
+
+// executePreparedQuery executes a prepared query in the local
+// datacenter, the query may fail over to other datacenters.
+func (p *consulProvider) executePreparedQuery(ctx context.Context, query string) ([]*types.ServiceInstance, error) {
+	response, _, err := consul.GetClient().PreparedQuery().Execute(query, (&capi.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Failovers > 0 {
+		glog.V(100).Infof("Prepared query %s failed over to datacenter %s.", query, response.Datacenter)
+	}
+
+	instances := make([]*types.ServiceInstance, 0, len(response.Nodes))
+	for i := range response.Nodes {
+		instances = append(instances, catalogServiceToInstance(healthEntryToCatalogService(&response.Nodes[i]), p.config))
+	}
+
+	return instances, nil
+}
+
+// preparedQueryPlaceholder is an instance that only carries the labels
+// of a service and a discovery error or warning, for services whose
+// prepared query did not return any node.
+func preparedQueryPlaceholder(serviceName string, tags []string) *types.ServiceInstance {
+	return &types.ServiceInstance{
+		ID:          serviceName,
+		ServiceName: serviceName,
+		Tags:        tags,
+		Unhealthy:   true,
+	}
+}
+
+// applyPreparedQueries replaces the instances of services that declare a
+// prepared query with its results, and adds the services of the prepared
+// queries in the configuration.
+//
+// The results are rendered as local nodes, as the query already
+// applied its own failover policy. A query that fails only fails its
+// own service, and a query without results keeps the backend of its
+// service without servers, both are reported for the service.
+func (p *consulProvider) applyPreparedQueries(ctx context.Context, result map[string][]*types.ServiceInstance) {
+	queries := make(map[string]string)
+
+	for serviceName, instances := range result {
+		for _, instance := range instances {
+			if instance.Remote {
+				continue
+			}
+
+			if query := preparedQueryLabel(instance, p.config.Prefix); query != "" {
+				queries[serviceName] = query
+			}
+
+			break
+		}
+	}
+
+	for serviceName, preparedQuery := range p.config.Consul.PreparedQueries {
+		queries[serviceName] = preparedQuery.Query
+	}
+
+	digests := make(map[string][sha256.Size]byte, len(queries))
+
+	for _, serviceName := range slices.Sorted(maps.Keys(queries)) {
+		query := queries[serviceName]
+
+		var tags []string
+		if preparedQuery, ok := p.config.Consul.PreparedQueries[serviceName]; ok && len(preparedQuery.Labels) != 0 {
+			tags = labelsToTags(preparedQuery.Labels, p.config.Prefix)
+		}
+
+		instances, err := p.executePreparedQuery(ctx, query)
+		if err != nil {
+			glog.Errorf("Got error when executing prepared query %s of service %s: %v", query, serviceName, err)
+
+			// Polled like any other query, so that the service
+			// is refreshed as soon as the query succeeds.
+			digests[query] = [sha256.Size]byte{}
+
+			placeholder := preparedQueryPlaceholder(serviceName, tags)
+			placeholder.Error = fmt.Sprintf("Prepared query %s failed: %v", query, err)
+
+			result[serviceName] = []*types.ServiceInstance{placeholder}
+
+			continue
+		}
+
+		digests[query] = instancesDigest(instances)
+
+		if len(instances) == 0 {
+			placeholder := preparedQueryPlaceholder(serviceName, tags)
+			placeholder.Warning = fmt.Sprintf("Prepared query %s returned no nodes, the backend has no servers", query)
+
+			// Keep the labels of the instances in the Catalog, if any.
+			if tags == nil {
+				for _, instance := range result[serviceName] {
+					if !instance.Remote {
+						placeholder.Tags, placeholder.Meta = instance.Tags, instance.Meta
+
+						break
+					}
+				}
+			}
+
+			result[serviceName] = []*types.ServiceInstance{placeholder}
+
+			continue
+		}
+
+		if tags != nil {
+			for _, instance := range instances {
+				instance.Tags = tags
+			}
+		}
+
+		result[serviceName] = instances
+	}
+
+	p.lock.Lock()
+	p.preparedQueryDigests = digests
+	p.lock.Unlock()
+}
+
+// watchPreparedQueries polls the prepared queries used by the last fetch,
+// and notifies as soon as the results of one of them changed.
+func (p *consulProvider) watchPreparedQueries(ctx context.Context, notify func()) {
+	for sleepContext(ctx, *p.config.Consul.PreparedQueryPollInterval) {
+		p.lock.Lock()
+		digests := p.preparedQueryDigests
+		p.lock.Unlock()
+
+		for query, digest := range digests {
+			instances, err := p.executePreparedQuery(ctx, query)
+			if err != nil {
+				if ctx.Err() == nil {
+					glog.Errorf("Got error when polling prepared query %s: %v", query, err)
+				}
+
+				continue
+			}
+
+			if instancesDigest(instances) != digest {
+				glog.V(100).Infof("Results of prepared query %s changed.", query)
+
+				notify()
+
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+)
+
+// consulWatcher long-polls the Consul Catalog of every datacenter
+// with blocking queries and emits an event whenever the list of
+// enabled services or the instances of any of them change.
+type consulWatcher struct {
+	config      *configuration.Config
+	datacenters []string
+
+	pending chan struct{}
+	events  chan struct{}
+
+	lock           sync.Mutex
+	serviceCancels map[serviceWatchKey]context.CancelFunc
+	serviceWait    sync.WaitGroup
+}
+
+// serviceWatchKey identifies the watch of a service in a datacenter.
+type serviceWatchKey struct {
+	datacenter string
+	service    string
+}
+
+// newConsulWatcher creates a watcher for the datacenters,
+// an empty datacenter is the local one.
+func newConsulWatcher(config *configuration.Config, datacenters []string) *consulWatcher {
+	return &consulWatcher{
+		config:         config,
+		datacenters:    datacenters,
+		pending:        make(chan struct{}, 1),
+		events:         make(chan struct{}, 1),
+		serviceCancels: make(map[serviceWatchKey]context.CancelFunc),
+	}
+}
+
+// Events returns the channel change events are sent to.
+// Events are coalesced and rate limited, and the channel is
+// closed once Run returns.
+func (w *consulWatcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Run watches the Catalog until the context is cancelled.
+func (w *consulWatcher) Run(ctx context.Context) {
+	glog.Infoln("Starting Consul Catalog watch...")
+
+	var dispatchWait sync.WaitGroup
+
+	dispatchWait.Add(1)
+	go func() {
+		defer dispatchWait.Done()
+
+		w.dispatch(ctx)
+	}()
+
+	var datacenterWait sync.WaitGroup
+
+	for _, datacenter := range w.datacenters {
+		datacenterWait.Add(1)
+		go func() {
+			defer datacenterWait.Done()
+
+			w.watchServices(ctx, datacenter)
+		}()
+	}
+
+	datacenterWait.Wait()
+
+	w.lock.Lock()
+	for key, cancel := range w.serviceCancels {
+		cancel()
+
+		delete(w.serviceCancels, key)
+	}
+	w.lock.Unlock()
+
+	w.serviceWait.Wait()
+	dispatchWait.Wait()
+
+	close(w.events)
+
+	glog.Infoln("Stopped Consul Catalog watch.")
+}
+
+// nextWaitIndex sanitizes the index returned by a blocking query.
+//
+// The index is reset if it goes backwards (e.g. after a snapshot restore)
+// and is never allowed to be 0, as that would not block.
+func nextWaitIndex(previous, current uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+
+	if current < 1 {
+		return 1
+	}
+
+	return current
+}
+
+// sleepContext sleeps for the duration and returns false
+// if the context was cancelled in the meantime.
+func sleepContext(ctx context.Context, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (w *consulWatcher) notify() {
+	select {
+	case w.pending <- struct{}{}:
+	default: // An event is already pending.
+	}
+}
+
+func (w *consulWatcher) dispatch(ctx context.Context) {
+	var lastEvent time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.pending:
+		}
+
+		if wait := *w.config.Consul.WatchRateLimit - time.Since(lastEvent); wait > 0 {
+			glog.V(100).Infof("Rate limiting Catalog watch event for %s...", wait)
+
+			if !sleepContext(ctx, wait) {
+				return
+			}
+		}
+
+		lastEvent = time.Now()
+
+		select {
+		case w.events <- struct{}{}:
+		default: // The consumer has not picked up the previous event yet.
+		}
+	}
+}
+
+// datacenterName gets the name of the datacenter for logging.
+func datacenterName(datacenter string) string {
+	if datacenter == "" {
+		return "local"
+	}
+
+	return datacenter
+}
+
+func (w *consulWatcher) watchServices(ctx context.Context, datacenter string) {
+	var index uint64
+
+	for ctx.Err() == nil {
+		services, meta, err := fetchServiceNames(ctx, w.config, datacenter, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			glog.Errorf("Got error when watching Consul services in %s datacenter: %v", datacenterName(datacenter), err)
+
+			sleepContext(ctx, *w.config.Consul.WatchRetryInterval)
+
+			continue
+		}
+
+		nextIndex := nextWaitIndex(index, meta.LastIndex)
+		changed := w.syncServiceWatches(ctx, datacenter, services)
+
+		if index != 0 && (nextIndex != index || changed) {
+			glog.V(100).Infof("Consul services in %s datacenter changed (index %d -> %d).", datacenterName(datacenter), index, nextIndex)
+
+			w.notify()
+		}
+
+		index = nextIndex
+	}
+}
+
+func (w *consulWatcher) syncServiceWatches(ctx context.Context, datacenter string, services map[string][]string) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	changed := false
+
+	for key, cancel := range w.serviceCancels {
+		if key.datacenter != datacenter {
+			continue
+		}
+
+		if _, ok := services[key.service]; ok {
+			continue
+		}
+
+		glog.V(100).Infof("Stopping watch for removed service %s in %s datacenter.", key.service, datacenterName(datacenter))
+
+		cancel()
+		delete(w.serviceCancels, key)
+
+		changed = true
+	}
+
+	for service := range services {
+		key := serviceWatchKey{datacenter: datacenter, service: service}
+		if _, ok := w.serviceCancels[key]; ok {
+			continue
+		}
+
+		glog.V(100).Infof("Starting watch for service %s in %s datacenter.", service, datacenterName(datacenter))
+
+		serviceCtx, cancel := context.WithCancel(ctx)
+		w.serviceCancels[key] = cancel
+
+		w.serviceWait.Add(1)
+		go func() {
+			defer w.serviceWait.Done()
+
+			w.watchService(serviceCtx, datacenter, service)
+		}()
+
+		changed = true
+	}
+
+	return changed
+}
+
+func (w *consulWatcher) watchService(ctx context.Context, datacenter, service string) {
+	var index uint64
+
+	for ctx.Err() == nil {
+		_, meta, err := fetchServiceInstances(ctx, service, w.config, datacenter, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			glog.Errorf("Got error when watching Consul service %s in %s datacenter: %v", service, datacenterName(datacenter), err)
+
+			sleepContext(ctx, *w.config.Consul.WatchRetryInterval)
+
+			continue
+		}
+
+		nextIndex := nextWaitIndex(index, meta.LastIndex)
+
+		if index != 0 && nextIndex != index {
+			glog.V(100).Infof("Consul service %s in %s datacenter changed (index %d -> %d).", service, datacenterName(datacenter), index, nextIndex)
+
+			w.notify()
+		}
+
+		index = nextIndex
+	}
+}
@@ -0,0 +1,453 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	dnsResolvConfPath = "/etc/resolv.conf"
+	dnsUDPSize        = 4096
+)
+
+// dnsCacheEntry is the resolved instances of a
+// service, cached until the smallest TTL expires.
+type dnsCacheEntry struct {
+	instances []*types.ServiceInstance
+	expires   time.Time
+}
+
+// dnsProvider discovers services from DNS SRV records
+// listed in the configuration.
+type dnsProvider struct {
+	config      *configuration.Config
+	nameservers []string
+
+	lock  sync.Mutex
+	cache map[string]*dnsCacheEntry
+}
+
+// readResolvConfNameservers reads the nameservers of the system resolver.
+func readResolvConfNameservers() ([]string, error) {
+	file, err := os.Open(dnsResolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var nameservers []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			nameservers = append(nameservers, fields[1])
+		}
+	}
+
+	return nameservers, scanner.Err()
+}
+
+func newDNSProvider(config *configuration.Config) (*dnsProvider, error) {
+	if len(config.DNS.Services) == 0 {
+		return nil, fmt.Errorf("config.DNS.Services must have at least one entry when using the dns provider!")
+	}
+
+	nameservers := config.DNS.Nameservers
+	if len(nameservers) == 0 {
+		var err error
+		if nameservers, err = readResolvConfNameservers(); err != nil {
+			return nil, err
+		}
+
+		if len(nameservers) == 0 {
+			return nil, fmt.Errorf("No nameservers found in %s, config.DNS.Nameservers must be specified!", dnsResolvConfPath)
+		}
+	}
+
+	provider := &dnsProvider{
+		config: config,
+		cache:  make(map[string]*dnsCacheEntry),
+	}
+
+	for _, nameserver := range nameservers {
+		if _, _, err := net.SplitHostPort(nameserver); err != nil {
+			nameserver = net.JoinHostPort(nameserver, "53")
+		}
+
+		provider.nameservers = append(provider.nameservers, nameserver)
+	}
+
+	glog.Infof("Initializing DNS provider with nameservers %s", strings.Join(provider.nameservers, ", "))
+
+	return provider, nil
+}
+
+func (p *dnsProvider) Name() string {
+	return configuration.ProviderDNS
+}
+
+// exchangeWith sends the query to a single nameserver.
+func (p *dnsProvider) exchangeWith(ctx context.Context, nameserver, network string, query []byte, id uint16) (*dnsmessage.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, *p.config.DNS.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, network, nameserver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	buffer := make([]byte, 65535)
+
+	var length int
+
+	if network == "tcp" {
+		// Messages over TCP are prefixed with their length.
+		if _, err = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)); err != nil {
+			return nil, err
+		}
+
+		if _, err = io.ReadFull(conn, buffer[:2]); err != nil {
+			return nil, err
+		}
+
+		length = int(binary.BigEndian.Uint16(buffer[:2]))
+
+		if _, err = io.ReadFull(conn, buffer[:length]); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err = conn.Write(query); err != nil {
+			return nil, err
+		}
+
+		if length, err = conn.Read(buffer); err != nil {
+			return nil, err
+		}
+	}
+
+	var response dnsmessage.Message
+	if err = response.Unpack(buffer[:length]); err != nil {
+		return nil, err
+	}
+
+	if response.ID != id {
+		return nil, fmt.Errorf("Got response with ID %d to query with ID %d", response.ID, id)
+	}
+
+	return &response, nil
+}
+
+// exchange queries the nameservers in order until one answers,
+// retrying over TCP if the UDP response was truncated. Nameservers
+// that fail or refuse to answer are skipped like unreachable ones.
+func (p *dnsProvider) exchange(ctx context.Context, name string, queryType dnsmessage.Type) (*dnsmessage.Message, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	questionName, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uint16(rand.Uint32())
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	builder.EnableCompression()
+
+	if err = builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+
+	if err = builder.Question(dnsmessage.Question{Name: questionName, Type: queryType, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+
+	if err = builder.StartAdditionals(); err != nil {
+		return nil, err
+	}
+
+	var optHeader dnsmessage.ResourceHeader
+	if err = optHeader.SetEDNS0(dnsUDPSize, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+
+	if err = builder.OPTResource(optHeader, dnsmessage.OPTResource{}); err != nil {
+		return nil, err
+	}
+
+	query, err := builder.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+
+	for _, nameserver := range p.nameservers {
+		response, err := p.exchangeWith(ctx, nameserver, "udp", query, id)
+		if err == nil && response.Truncated {
+			response, err = p.exchangeWith(ctx, nameserver, "tcp", query, id)
+		}
+
+		if err != nil {
+			lastErr = fmt.Errorf("nameserver %s: %w", nameserver, err)
+
+			continue
+		}
+
+		// The next nameserver may still answer for a failing or refusing one.
+		if response.RCode == dnsmessage.RCodeServerFailure || response.RCode == dnsmessage.RCodeRefused {
+			lastErr = fmt.Errorf("nameserver %s: Got %s when resolving %s", nameserver, response.RCode, name)
+
+			continue
+		}
+
+		if response.RCode != dnsmessage.RCodeSuccess {
+			return nil, fmt.Errorf("Got %s when resolving %s", response.RCode, name)
+		}
+
+		return response, nil
+	}
+
+	return nil, lastErr
+}
+
+// addressRecords collects the A and AAAA records of the resources.
+func addressRecords(resources []dnsmessage.Resource, addresses map[string][]netip.Addr, ttl *uint32) {
+	for _, resource := range resources {
+		var address netip.Addr
+
+		switch body := resource.Body.(type) {
+		case *dnsmessage.AResource:
+			address = netip.AddrFrom4(body.A)
+		case *dnsmessage.AAAAResource:
+			address = netip.AddrFrom16(body.AAAA)
+		default:
+			continue
+		}
+
+		name := strings.ToLower(resource.Header.Name.String())
+		addresses[name] = append(addresses[name], address)
+
+		*ttl = min(*ttl, resource.Header.TTL)
+	}
+}
+
+// resolveTarget resolves the address of an SRV target that
+// was not included in the additional section of the response.
+func (p *dnsProvider) resolveTarget(ctx context.Context, target string, ttl *uint32) (netip.Addr, error) {
+	for _, queryType := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		response, err := p.exchange(ctx, target, queryType)
+		if err != nil {
+			return netip.Addr{}, err
+		}
+
+		addresses := make(map[string][]netip.Addr)
+		addressRecords(response.Answers, addresses, ttl)
+
+		// The answers may go through CNAME records, any address will do.
+		for _, name := range slices.Sorted(maps.Keys(addresses)) {
+			return addresses[name][0], nil
+		}
+	}
+
+	return netip.Addr{}, fmt.Errorf("No address records found for %s", target)
+}
+
+// labelsToTags converts the routing labels of a
+// service to tags with the configured prefix.
+func labelsToTags(labels map[string]string, prefix string) []string {
+	tags := []string{prefix + ".enable=true"}
+
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		tags = append(tags, fmt.Sprintf("%s.%s=%s", prefix, key, labels[key]))
+	}
+
+	return tags
+}
+
+// resolveService resolves the instances of a service and the
+// smallest TTL of the records they were resolved from.
+func (p *dnsProvider) resolveService(ctx context.Context, serviceName string, service *configuration.DNSServiceConfig) ([]*types.ServiceInstance, time.Duration, error) {
+	response, err := p.exchange(ctx, service.Name, dnsmessage.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ttl := uint32(max(p.config.RefreshInterval.Seconds(), 1))
+
+	addresses := make(map[string][]netip.Addr)
+	addressRecords(response.Additionals, addresses, &ttl)
+
+	tags := labelsToTags(service.Labels, p.config.Prefix)
+	names := make(map[string]bool)
+
+	var instances []*types.ServiceInstance
+
+	for _, answer := range response.Answers {
+		srv, ok := answer.Body.(*dnsmessage.SRVResource)
+		if !ok {
+			continue
+		}
+
+		ttl = min(ttl, answer.Header.TTL)
+
+		target := strings.ToLower(srv.Target.String())
+		if target == "." {
+			continue // The service is explicitly not available.
+		}
+
+		var address netip.Addr
+		if targetAddresses, ok := addresses[target]; ok {
+			address = targetAddresses[0]
+		} else if address, err = p.resolveTarget(ctx, target, &ttl); err != nil {
+			glog.Errorf("Skipping target %s of service %s: %v", target, serviceName, err)
+
+			continue
+		}
+
+		name := strings.TrimSuffix(target, ".")
+		if names[name] {
+			name += "-" + strconv.Itoa(int(srv.Port))
+		}
+
+		names[name] = true
+
+		instances = append(instances, &types.ServiceInstance{
+			ID:          net.JoinHostPort(strings.TrimSuffix(target, "."), strconv.Itoa(int(srv.Port))),
+			ServiceName: serviceName,
+			Name:        name,
+			Address:     address.String(),
+			Port:        int(srv.Port),
+			Tags:        tags,
+		})
+	}
+
+	slices.SortFunc(instances, func(a, b *types.ServiceInstance) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return instances, max(time.Duration(ttl)*time.Second, *p.config.DNS.MinimumTTL), nil
+}
+
+func instancesEqual(a, b []*types.ServiceInstance) bool {
+	return slices.EqualFunc(a, b, func(a, b *types.ServiceInstance) bool {
+		return a.ID == b.ID && a.Name == b.Name && a.Address == b.Address && a.Port == b.Port
+	})
+}
+
+// resolveExpired re-resolves the services whose records expired, services
+// that fail to resolve keep their previous instances until they resolve again.
+//
+// It returns true if the instances of any service changed.
+func (p *dnsProvider) resolveExpired(ctx context.Context) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	changed := false
+
+	for serviceName, service := range p.config.DNS.Services {
+		entry, ok := p.cache[serviceName]
+		if ok && time.Now().Before(entry.expires) {
+			continue
+		}
+
+		instances, ttl, err := p.resolveService(ctx, serviceName, service)
+		if err != nil {
+			glog.Errorf("Got error when resolving %s for service %s: %v", service.Name, serviceName, err)
+
+			if ok {
+				entry.expires = time.Now().Add(*p.config.DNS.MinimumTTL)
+			}
+
+			continue
+		}
+
+		if !ok || !instancesEqual(entry.instances, instances) {
+			changed = true
+		}
+
+		p.cache[serviceName] = &dnsCacheEntry{
+			instances: instances,
+			expires:   time.Now().Add(ttl),
+		}
+	}
+
+	return changed
+}
+
+// FetchServices returns the instances of every service, only records
+// with an expired TTL are resolved again.
+func (p *dnsProvider) FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error) {
+	p.resolveExpired(ctx)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	result := make(map[string][]*types.ServiceInstance, len(p.cache))
+	for serviceName, entry := range p.cache {
+		if len(entry.instances) != 0 {
+			result[serviceName] = entry.instances
+		}
+	}
+
+	return result, nil
+}
+
+// nextExpiry returns the time the first cached service expires at.
+func (p *dnsProvider) nextExpiry() time.Time {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	next := time.Now().Add(*p.config.DNS.MinimumTTL)
+	for _, entry := range p.cache {
+		if entry.expires.Before(next) {
+			next = entry.expires
+		}
+	}
+
+	return next
+}
+
+// Watch re-resolves services as their TTL expires and
+// notifies if the resolved instances changed.
+func (p *dnsProvider) Watch(ctx context.Context, notify func()) {
+	for {
+		wait := max(time.Until(p.nextExpiry()), *p.config.DNS.MinimumTTL)
+		if !sleepContext(ctx, wait) {
+			return
+		}
+
+		if p.resolveExpired(ctx) {
+			glog.V(100).Infoln("DNS records changed.")
+
+			notify()
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package providers
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const testSRVName = "_http._tcp.web.service.consul."
+
+// fakeDNS is a nameserver answering over UDP and TCP on the same port
+// from a fixed zone, which can truncate its UDP responses or fail them.
+type fakeDNS struct {
+	t       *testing.T
+	address string
+
+	lock     sync.Mutex
+	rcode    dnsmessage.RCode
+	truncate bool
+	queries  []string
+
+	answers     map[string][]dnsmessage.Resource
+	additionals map[string][]dnsmessage.Resource
+}
+
+func newFakeDNS(t *testing.T) *fakeDNS {
+	server := &fakeDNS{
+		t:           t,
+		answers:     make(map[string][]dnsmessage.Resource),
+		additionals: make(map[string][]dnsmessage.Resource),
+	}
+
+	// The TCP fallback goes to the port of the UDP nameserver.
+	for attempt := 0; ; attempt++ {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			udp.Close()
+
+			if attempt == 10 {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		t.Cleanup(func() {
+			udp.Close()
+			tcp.Close()
+		})
+
+		server.address = udp.LocalAddr().String()
+
+		go server.serveUDP(udp)
+		go server.serveTCP(tcp)
+
+		return server
+	}
+}
+
+func zoneKey(name string, queryType dnsmessage.Type) string {
+	return name + " " + queryType.String()
+}
+
+func (s *fakeDNS) add(answer dnsmessage.Resource, additionals ...dnsmessage.Resource) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := zoneKey(answer.Header.Name.String(), answer.Header.Type)
+
+	s.answers[key] = append(s.answers[key], answer)
+	s.additionals[key] = append(s.additionals[key], additionals...)
+}
+
+// requests gets the queries answered so far, as "<network> <type> <name>".
+func (s *fakeDNS) requests() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return slices.Clone(s.queries)
+}
+
+func (s *fakeDNS) handle(network string, content []byte) []byte {
+	var query dnsmessage.Message
+	if err := query.Unpack(content); err != nil || len(query.Questions) != 1 {
+		s.t.Errorf("Got invalid query over %s: %v", network, err)
+
+		return nil
+	}
+
+	question := query.Questions[0]
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.queries = append(s.queries, fmt.Sprintf("%s %s %s", network, question.Type, question.Name))
+
+	response := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: s.rcode},
+		Questions: query.Questions,
+	}
+
+	if s.rcode == dnsmessage.RCodeSuccess {
+		if network == "udp" && s.truncate {
+			response.Truncated = true
+		} else {
+			key := zoneKey(question.Name.String(), question.Type)
+
+			response.Answers = s.answers[key]
+			response.Additionals = s.additionals[key]
+
+			if len(response.Answers) == 0 {
+				response.RCode = dnsmessage.RCodeNameError
+			}
+		}
+	}
+
+	packed, err := response.Pack()
+	if err != nil {
+		s.t.Errorf("Got error when packing response: %v", err)
+	}
+
+	return packed
+}
+
+func (s *fakeDNS) serveUDP(conn net.PacketConn) {
+	buffer := make([]byte, 65535)
+
+	for {
+		length, address, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+
+		if response := s.handle("udp", buffer[:length]); response != nil {
+			conn.WriteTo(response, address)
+		}
+	}
+}
+
+func (s *fakeDNS) serveTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+
+			content := make([]byte, binary.BigEndian.Uint16(header))
+			if _, err := io.ReadFull(conn, content); err != nil {
+				return
+			}
+
+			if response := s.handle("tcp", content); response != nil {
+				conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...))
+			}
+		}()
+	}
+}
+
+func srvRecord(name, target string, port uint16, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.SRVResource{Target: dnsmessage.MustNewName(target), Port: port},
+	}
+}
+
+func aRecord(name string, address [4]byte, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: address},
+	}
+}
+
+func newTestDNSProvider(t *testing.T, services map[string]string, nameservers ...string) *dnsProvider {
+	refreshInterval := time.Minute
+	timeout := time.Second
+	minimumTTL := 50 * time.Millisecond
+
+	dnsServices := make(map[string]*configuration.DNSServiceConfig, len(services))
+	for serviceName, name := range services {
+		dnsServices[serviceName] = &configuration.DNSServiceConfig{
+			Name:   name,
+			Labels: map[string]string{"fe.fqdn": serviceName + ".example.com"},
+		}
+	}
+
+	provider, err := newDNSProvider(&configuration.Config{
+		Prefix:          "haproxy",
+		RefreshInterval: &refreshInterval,
+		DNS: &configuration.DNSConfig{
+			Nameservers: nameservers,
+			Timeout:     &timeout,
+			MinimumTTL:  &minimumTTL,
+			Services:    dnsServices,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Got error when creating the DNS provider: %v", err)
+	}
+
+	return provider
+}
+
+func webInstance(target string, address string, port int) *types.ServiceInstance {
+	return &types.ServiceInstance{
+		ID:          fmt.Sprintf("%s:%d", target, port),
+		ServiceName: "web",
+		Name:        target,
+		Address:     address,
+		Port:        port,
+		Tags:        []string{"haproxy.enable=true", "haproxy.fe.fqdn=web.example.com"},
+	}
+}
+
+func TestDNSResolveService(t *testing.T) {
+	server := newFakeDNS(t)
+
+	server.add(srvRecord(testSRVName, "node-a.node.consul.", 8080, 30), aRecord("node-a.node.consul.", [4]byte{10, 0, 0, 1}, 20))
+	server.add(srvRecord(testSRVName, "node-a.node.consul.", 9090, 30))
+	server.add(srvRecord(testSRVName, "node-b.node.consul.", 8081, 30))
+	server.add(srvRecord(testSRVName, ".", 8082, 30))
+	server.add(aRecord("node-b.node.consul.", [4]byte{10, 0, 0, 2}, 10))
+
+	provider := newTestDNSProvider(t, map[string]string{"web": testSRVName}, server.address)
+
+	instances, ttl, err := provider.resolveService(context.Background(), "web", provider.config.DNS.Services["web"])
+	if err != nil {
+		t.Fatalf("Got error when resolving the service: %v", err)
+	}
+
+	expected := []*types.ServiceInstance{
+		webInstance("node-a.node.consul", "10.0.0.1", 8080),
+		webInstance("node-a.node.consul", "10.0.0.1", 9090),
+		webInstance("node-b.node.consul", "10.0.0.2", 8081),
+	}
+
+	// Targets with several ports are named after the port.
+	expected[1].Name = "node-a.node.consul-9090"
+
+	if !reflect.DeepEqual(instances, expected) {
+		t.Errorf("Got instances %+v, expected %+v", instances, expected)
+	}
+
+	// The smallest TTL, of the address resolved separately.
+	if ttl != 10*time.Second {
+		t.Errorf("Got TTL %s, expected 10s", ttl)
+	}
+
+	// Only the target missing from the additional section is resolved.
+	expectedQueries := []string{"udp TypeSRV " + testSRVName, "udp TypeA node-b.node.consul."}
+	if queries := server.requests(); !reflect.DeepEqual(queries, expectedQueries) {
+		t.Errorf("Got queries %v, expected %v", queries, expectedQueries)
+	}
+}
+
+func TestDNSTruncatedResponse(t *testing.T) {
+	server := newFakeDNS(t)
+	server.truncate = true
+
+	server.add(srvRecord(testSRVName, "node-a.node.consul.", 8080, 30), aRecord("node-a.node.consul.", [4]byte{10, 0, 0, 1}, 30))
+
+	provider := newTestDNSProvider(t, map[string]string{"web": testSRVName}, server.address)
+
+	instances, _, err := provider.resolveService(context.Background(), "web", provider.config.DNS.Services["web"])
+	if err != nil {
+		t.Fatalf("Got error when resolving the service: %v", err)
+	}
+
+	if expected := []*types.ServiceInstance{webInstance("node-a.node.consul", "10.0.0.1", 8080)}; !reflect.DeepEqual(instances, expected) {
+		t.Errorf("Got instances %+v, expected %+v", instances, expected)
+	}
+
+	expectedQueries := []string{"udp TypeSRV " + testSRVName, "tcp TypeSRV " + testSRVName}
+	if queries := server.requests(); !reflect.DeepEqual(queries, expectedQueries) {
+		t.Errorf("Got queries %v, expected %v", queries, expectedQueries)
+	}
+}
+
+func TestDNSNameserverFailover(t *testing.T) {
+	tests := []struct {
+		name     string
+		rcode    dnsmessage.RCode
+		failover bool
+	}{
+		{name: "server failure", rcode: dnsmessage.RCodeServerFailure, failover: true},
+		{name: "refused", rcode: dnsmessage.RCodeRefused, failover: true},
+		{name: "name error", rcode: dnsmessage.RCodeNameError, failover: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			failing := newFakeDNS(t)
+			failing.rcode = test.rcode
+
+			server := newFakeDNS(t)
+			server.add(srvRecord(testSRVName, "node-a.node.consul.", 8080, 30), aRecord("node-a.node.consul.", [4]byte{10, 0, 0, 1}, 30))
+
+			provider := newTestDNSProvider(t, map[string]string{"web": testSRVName}, failing.address, server.address)
+
+			instances, _, err := provider.resolveService(context.Background(), "web", provider.config.DNS.Services["web"])
+
+			if !test.failover {
+				if err == nil {
+					t.Errorf("Expected an error when the first nameserver answers with %s", test.rcode)
+				}
+
+				if queries := server.requests(); len(queries) != 0 {
+					t.Errorf("Expected no queries to the second nameserver, got %v", queries)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Got error when resolving the service: %v", err)
+			}
+
+			if expected := []*types.ServiceInstance{webInstance("node-a.node.consul", "10.0.0.1", 8080)}; !reflect.DeepEqual(instances, expected) {
+				t.Errorf("Got instances %+v, expected %+v", instances, expected)
+			}
+
+			if queries := failing.requests(); len(queries) != 1 {
+				t.Errorf("Expected a single query to the failing nameserver, got %v", queries)
+			}
+		})
+	}
+}
+
+func TestDNSAllNameserversFail(t *testing.T) {
+	first := newFakeDNS(t)
+	first.rcode = dnsmessage.RCodeServerFailure
+
+	second := newFakeDNS(t)
+	second.rcode = dnsmessage.RCodeRefused
+
+	provider := newTestDNSProvider(t, map[string]string{"web": testSRVName}, first.address, second.address)
+
+	if _, _, err := provider.resolveService(context.Background(), "web", provider.config.DNS.Services["web"]); err == nil {
+		t.Errorf("Expected an error when every nameserver fails")
+	}
+}
+
+func TestDNSTTLExpiry(t *testing.T) {
+	const longSRVName = "_http._tcp.long.service.consul."
+
+	server := newFakeDNS(t)
+
+	// A TTL of 0 expires after the minimum TTL.
+	server.add(srvRecord(testSRVName, "node-a.node.consul.", 8080, 0), aRecord("node-a.node.consul.", [4]byte{10, 0, 0, 1}, 0))
+	server.add(srvRecord(longSRVName, "node-c.node.consul.", 8080, 3600), aRecord("node-c.node.consul.", [4]byte{10, 0, 0, 3}, 3600))
+
+	provider := newTestDNSProvider(t, map[string]string{"web": testSRVName, "long": longSRVName}, server.address)
+
+	if !provider.resolveExpired(context.Background()) {
+		t.Errorf("Expected the first resolution to report changes")
+	}
+
+	// Nothing expired yet.
+	if provider.resolveExpired(context.Background()) {
+		t.Errorf("Expected no changes before the records expire")
+	}
+
+	if queries := server.requests(); len(queries) != 2 {
+		t.Errorf("Expected a single query per service before the records expire, got %v", queries)
+	}
+
+	server.add(srvRecord(testSRVName, "node-b.node.consul.", 8080, 0), aRecord("node-b.node.consul.", [4]byte{10, 0, 0, 2}, 0))
+
+	time.Sleep(2 * *provider.config.DNS.MinimumTTL)
+
+	if !provider.resolveExpired(context.Background()) {
+		t.Errorf("Expected the expired records to be resolved again with changes")
+	}
+
+	if queries := server.requests(); len(queries) != 3 || queries[2] != "udp TypeSRV "+testSRVName {
+		t.Errorf("Expected only the expired service to be resolved again, got %v", queries)
+	}
+
+	result, err := provider.FetchServices(context.Background())
+	if err != nil {
+		t.Fatalf("Got error when fetching services: %v", err)
+	}
+
+	if instances := result["web"]; len(instances) != 2 {
+		t.Errorf("Got %d instances of web, expected 2", len(instances))
+	}
+
+	if instances := result["long"]; len(instances) != 1 {
+		t.Errorf("Got %d instances of long, expected 1", len(instances))
+	}
+}
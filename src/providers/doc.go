@@ -0,0 +1,3 @@
+// Package providers contains the service discovery
+// providers that services are fetched from.
+package providers
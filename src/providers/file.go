@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// fileProvider discovers services from a directory of Json or Yaml files.
+type fileProvider struct {
+	config *configuration.Config
+}
+
+func newFileProvider(config *configuration.Config) (*fileProvider, error) {
+	if config.File.Directory == "" {
+		return nil, fmt.Errorf("config.File.Directory must be specified when using the file provider!")
+	}
+
+	glog.Infof("Initializing file provider for directory %s", config.File.Directory)
+
+	return &fileProvider{config: config}, nil
+}
+
+func (p *fileProvider) Name() string {
+	return configuration.ProviderFile
+}
+
+func (p *fileProvider) listFiles() ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(p.config.File.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(entries, func(entry os.DirEntry) bool {
+		switch path.Ext(entry.Name()) {
+		case ".json", ".yml", ".yaml":
+			return entry.IsDir()
+		default:
+			return true
+		}
+	}), nil
+}
+
+func (p *fileProvider) FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error) {
+	files, err := p.listFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]*types.ServiceInstance)
+
+	for _, file := range files {
+		services, err := LoadSnapshot(filepath.Join(p.config.File.Directory, file.Name()), p.config)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name(), err)
+		}
+
+		for serviceName, instances := range services {
+			for _, instance := range instances {
+				if !isEnabled(instance.Tags, p.config.Prefix) {
+					continue
+				}
+
+				if instance.ServiceName == "" {
+					instance.ServiceName = serviceName
+				}
+
+				result[serviceName] = append(result[serviceName], instance)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// directoryDigest computes a digest of the name, size and
+// modification time of every file in the directory.
+func (p *fileProvider) directoryDigest() ([sha256.Size]byte, error) {
+	files, err := p.listFiles()
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	hash := sha256.New()
+
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+
+		fmt.Fprintf(hash, "%s:%d:%d\n", file.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return [sha256.Size]byte(hash.Sum(nil)), nil
+}
+
+// Watch polls the directory for changes.
+func (p *fileProvider) Watch(ctx context.Context, notify func()) {
+	lastDigest, err := p.directoryDigest()
+	if err != nil {
+		glog.Errorf("Got error when watching directory %s: %v", p.config.File.Directory, err)
+	}
+
+	ticker := time.NewTicker(*p.config.File.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		digest, err := p.directoryDigest()
+		if err != nil {
+			glog.Errorf("Got error when watching directory %s: %v", p.config.File.Directory, err)
+
+			continue
+		}
+
+		if digest != lastDigest {
+			glog.V(100).Infof("Directory %s changed.", p.config.File.Directory)
+
+			lastDigest = digest
+
+			notify()
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// nomadServiceStub is an entry of the Nomad service list.
+type nomadServiceStub struct {
+	ServiceName string
+	Tags        []string
+}
+
+// nomadNamespaceServices is the Nomad service list of a namespace.
+type nomadNamespaceServices struct {
+	Namespace string
+	Services  []*nomadServiceStub
+}
+
+// nomadServiceRegistration is an instance of a Nomad service.
+type nomadServiceRegistration struct {
+	ID          string
+	ServiceName string
+	Namespace   string
+	NodeID      string
+	Datacenter  string
+	JobID       string
+	AllocID     string
+	Tags        []string
+	Address     string
+	Port        int
+	ModifyIndex uint64
+}
+
+// nomadProvider discovers services from Nomad native service discovery.
+type nomadProvider struct {
+	config *configuration.Config
+	client *http.Client
+}
+
+func newNomadTLSConfig(config *configuration.NomadTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CAFile != "" {
+		caPem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("No certificates found in %s", config.CAFile)
+		}
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func newNomadProvider(config *configuration.Config) (*nomadProvider, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.Nomad.TLSConfig != nil {
+		tlsConfig, err := newNomadTLSConfig(config.Nomad.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	glog.Infof("Initializing Nomad client for Address %s", config.Nomad.Address)
+
+	return &nomadProvider{
+		config: config,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (p *nomadProvider) Name() string {
+	return configuration.ProviderNomad
+}
+
+// get queries the Nomad HTTP API and decodes the response into result.
+//
+// If waitIndex is not 0 the query blocks until the index
+// changes, the index of the response is returned.
+func (p *nomadProvider) get(ctx context.Context, path string, namespace string, waitIndex uint64, result any) (uint64, error) {
+	query := url.Values{}
+	query.Set("namespace", namespace)
+
+	if p.config.Nomad.Region != "" {
+		query.Set("region", p.config.Nomad.Region)
+	}
+
+	if waitIndex != 0 {
+		query.Set("index", strconv.FormatUint(waitIndex, 10))
+
+		if p.config.Nomad.WaitTime != 0 {
+			query.Set("wait", p.config.Nomad.WaitTime.String())
+		}
+	}
+
+	endpoint := strings.TrimSuffix(p.config.Nomad.Address, "/") + path + "?" + query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if p.config.Nomad.Token != "" {
+		request.Header.Set("X-Nomad-Token", p.config.Nomad.Token)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return 0, fmt.Errorf("Nomad returned %s for %s: %s", response.Status, path, strings.TrimSpace(string(body)))
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(result); err != nil {
+		return 0, err
+	}
+
+	index, err := strconv.ParseUint(response.Header.Get("X-Nomad-Index"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Nomad returned an invalid X-Nomad-Index for %s: %w", path, err)
+	}
+
+	return index, nil
+}
+
+func (p *nomadProvider) fetchServiceNames(ctx context.Context, waitIndex uint64) ([]*nomadNamespaceServices, uint64, error) {
+	var namespaces []*nomadNamespaceServices
+
+	index, err := p.get(ctx, "/v1/services", p.config.Nomad.Namespace, waitIndex, &namespaces)
+
+	return namespaces, index, err
+}
+
+func (p *nomadProvider) fetchServiceRegistrations(ctx context.Context, namespace, service string) ([]*nomadServiceRegistration, error) {
+	var registrations []*nomadServiceRegistration
+
+	_, err := p.get(ctx, "/v1/service/"+url.PathEscape(service), namespace, 0, &registrations)
+
+	return registrations, err
+}
+
+func nomadRegistrationToInstance(registration *nomadServiceRegistration) *types.ServiceInstance {
+	return &types.ServiceInstance{
+		ID:          registration.ID,
+		ServiceName: registration.ServiceName,
+		Name:        registration.AllocID,
+		Address:     registration.Address,
+		Port:        registration.Port,
+		Tags:        registration.Tags,
+		Datacenter:  registration.Datacenter,
+		ModifyIndex: registration.ModifyIndex,
+	}
+}
+
+// FetchServices fetches a map of service name to service instance from Nomad,
+// services with the same name in different namespaces are merged.
+// This method does not block, see Watch for change notifications.
+func (p *nomadProvider) FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error) {
+	namespaces, _, err := p.fetchServiceNames(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]*types.ServiceInstance)
+
+	for _, namespace := range namespaces {
+		for _, service := range namespace.Services {
+			// The tags of the list are the union of the tags of every instance.
+			if !isEnabled(service.Tags, p.config.Prefix) {
+				continue
+			}
+
+			registrations, err := p.fetchServiceRegistrations(ctx, namespace.Namespace, service.ServiceName)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, registration := range registrations {
+				if !isEnabled(registration.Tags, p.config.Prefix) {
+					continue
+				}
+
+				result[service.ServiceName] = append(result[service.ServiceName], nomadRegistrationToInstance(registration))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Watch long-polls the Nomad service list with blocking queries, unless
+// disabled in the configuration. The index of the list changes whenever
+// any service registration changes, so a single query covers every
+// service.
+func (p *nomadProvider) Watch(ctx context.Context, notify func()) {
+	if !*p.config.Nomad.Watch {
+		return
+	}
+
+	glog.Infoln("Starting Nomad service watch...")
+
+	var index uint64
+
+	for ctx.Err() == nil {
+		_, lastIndex, err := p.fetchServiceNames(ctx, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+
+			glog.Errorf("Got error when watching Nomad services: %v", err)
+
+			sleepContext(ctx, *p.config.Nomad.WatchRetryInterval)
+
+			continue
+		}
+
+		nextIndex := nextWaitIndex(index, lastIndex)
+
+		if index != 0 && nextIndex != index {
+			glog.V(100).Infof("Nomad services changed (index %d -> %d).", index, nextIndex)
+
+			notify()
+
+			// Changes made in the meantime are picked up by the next query.
+			sleepContext(ctx, *p.config.Nomad.WatchRateLimit)
+		}
+
+		index = nextIndex
+	}
+
+	glog.Infoln("Stopped Nomad service watch.")
+}
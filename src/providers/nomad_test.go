@@ -0,0 +1,273 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// fakeNomad is a Nomad HTTP API serving a fixed set of services
+// whose index can be bumped to wake up blocking queries.
+type fakeNomad struct {
+	t *testing.T
+
+	lock     sync.Mutex
+	index    uint64
+	changed  chan struct{}
+	requests []string
+	blocked  int
+
+	services      []*nomadNamespaceServices
+	registrations map[string][]*nomadServiceRegistration
+}
+
+func newFakeNomad(t *testing.T) (*fakeNomad, *httptest.Server) {
+	nomad := &fakeNomad{
+		t:       t,
+		index:   10,
+		changed: make(chan struct{}),
+		services: []*nomadNamespaceServices{
+			{
+				Namespace: "default",
+				Services: []*nomadServiceStub{
+					{ServiceName: "web", Tags: []string{"haproxy.enable=true", "haproxy.fe.fqdn=web.example.com"}},
+					{ServiceName: "internal", Tags: []string{"metrics"}},
+				},
+			},
+		},
+		registrations: map[string][]*nomadServiceRegistration{
+			"web": {
+				{
+					ID:          "_nomad-task-1",
+					ServiceName: "web",
+					Namespace:   "default",
+					Datacenter:  "dc1",
+					AllocID:     "alloc-1",
+					Tags:        []string{"haproxy.enable=true", "haproxy.fe.fqdn=web.example.com"},
+					Address:     "10.0.0.1",
+					Port:        8080,
+					ModifyIndex: 7,
+				},
+				{
+					ID:          "_nomad-task-2",
+					ServiceName: "web",
+					Namespace:   "default",
+					Datacenter:  "dc1",
+					AllocID:     "alloc-2",
+					Tags:        []string{"haproxy.enable=false"},
+					Address:     "10.0.0.2",
+					Port:        8080,
+					ModifyIndex: 8,
+				},
+			},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/services", nomad.handleServices)
+	mux.HandleFunc("GET /v1/service/{name}", nomad.handleService)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return nomad, server
+}
+
+// bump changes the index of the services, waking up blocking queries.
+func (n *fakeNomad) bump() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.index++
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+func (n *fakeNomad) record(r *http.Request) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.requests = append(n.requests, r.URL.Path)
+
+	if token := r.Header.Get("X-Nomad-Token"); token != "secret" {
+		n.t.Errorf("Request to %s has token %q, expected secret", r.URL.Path, token)
+	}
+
+	if namespace := r.URL.Query().Get("namespace"); namespace != "default" {
+		n.t.Errorf("Request to %s has namespace %q, expected default", r.URL.Path, namespace)
+	}
+}
+
+func (n *fakeNomad) handleServices(w http.ResponseWriter, r *http.Request) {
+	n.record(r)
+
+	n.lock.Lock()
+	index, changed := n.index, n.changed
+	n.lock.Unlock()
+
+	// Blocking query, wait for the index to move past the given one.
+	if waitIndex, err := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); err == nil && waitIndex >= index {
+		n.lock.Lock()
+		n.blocked++
+		n.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+
+		n.lock.Lock()
+		index = n.index
+		n.lock.Unlock()
+	}
+
+	w.Header().Set("X-Nomad-Index", strconv.FormatUint(index, 10))
+	json.NewEncoder(w).Encode(n.services)
+}
+
+func (n *fakeNomad) handleService(w http.ResponseWriter, r *http.Request) {
+	n.record(r)
+
+	registrations, ok := n.registrations[r.PathValue("name")]
+	if !ok {
+		http.Error(w, "service not found", http.StatusNotFound)
+
+		return
+	}
+
+	w.Header().Set("X-Nomad-Index", "10")
+	json.NewEncoder(w).Encode(registrations)
+}
+
+func newTestNomadProvider(t *testing.T, address string) *nomadProvider {
+	watch := true
+	rateLimit := time.Millisecond
+	retryInterval := time.Millisecond
+
+	provider, err := newNomadProvider(&configuration.Config{
+		Prefix: "haproxy",
+		Nomad: &configuration.NomadConfig{
+			Address:            address,
+			Namespace:          "default",
+			Token:              "secret",
+			Watch:              &watch,
+			WatchRateLimit:     &rateLimit,
+			WatchRetryInterval: &retryInterval,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Got error when creating the Nomad provider: %v", err)
+	}
+
+	return provider
+}
+
+func TestNomadFetchServices(t *testing.T) {
+	nomad, server := newFakeNomad(t)
+	provider := newTestNomadProvider(t, server.URL)
+
+	result, err := provider.FetchServices(context.Background())
+	if err != nil {
+		t.Fatalf("Got error when fetching services: %v", err)
+	}
+
+	expected := map[string][]*types.ServiceInstance{
+		"web": {
+			{
+				ID:          "_nomad-task-1",
+				ServiceName: "web",
+				Name:        "alloc-1",
+				Address:     "10.0.0.1",
+				Port:        8080,
+				Tags:        []string{"haproxy.enable=true", "haproxy.fe.fqdn=web.example.com"},
+				Datacenter:  "dc1",
+				ModifyIndex: 7,
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		got, _ := json.Marshal(result)
+		want, _ := json.Marshal(expected)
+
+		t.Errorf("Got services %s, expected %s", got, want)
+	}
+
+	// Services that are not enabled are never fetched.
+	if requests := []string{"/v1/services", "/v1/service/web"}; !reflect.DeepEqual(nomad.requests, requests) {
+		t.Errorf("Got requests %v, expected %v", nomad.requests, requests)
+	}
+}
+
+func TestNomadFetchServicesError(t *testing.T) {
+	nomad, server := newFakeNomad(t)
+	provider := newTestNomadProvider(t, server.URL)
+
+	nomad.services[0].Services = append(nomad.services[0].Services, &nomadServiceStub{ServiceName: "gone", Tags: []string{"haproxy.enable=true"}})
+
+	if _, err := provider.FetchServices(context.Background()); err == nil {
+		t.Errorf("Expected an error when a service cannot be fetched")
+	}
+}
+
+func TestNomadWatch(t *testing.T) {
+	nomad, server := newFakeNomad(t)
+	provider := newTestNomadProvider(t, server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	notifications := make(chan struct{}, 1)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		provider.Watch(ctx, func() { notifications <- struct{}{} })
+	}()
+
+	// The first query only gets the current index.
+	select {
+	case <-notifications:
+		t.Fatalf("Got a notification before the services changed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	nomad.bump()
+
+	select {
+	case <-notifications:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Got no notification after the services changed")
+	}
+
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch did not stop after the context was cancelled")
+	}
+
+	nomad.lock.Lock()
+	defer nomad.lock.Unlock()
+
+	if nomad.blocked == 0 {
+		t.Errorf("Watch never made a blocking query")
+	}
+
+	for _, request := range nomad.requests {
+		if request != "/v1/services" {
+			t.Errorf("Watch requested %s, expected only /v1/services", request)
+		}
+	}
+}
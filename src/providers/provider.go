@@ -0,0 +1,141 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/metrics"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// Provider is a service discovery provider.
+type Provider interface {
+	// Name is the name of the provider.
+	Name() string
+
+	// FetchServices fetches a map of service name to the
+	// instances of every service enabled for routing.
+	FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error)
+
+	// Watch blocks until the context is cancelled and calls
+	// notify whenever the services may have changed.
+	//
+	// Providers that cannot watch return immediately.
+	Watch(ctx context.Context, notify func())
+}
+
+// New creates the providers listed in the configuration, if there
+// is more than one they are combined into a single provider.
+func New(config *configuration.Config) (Provider, error) {
+	var providers []Provider
+
+	for _, name := range config.Providers {
+		var provider Provider
+		var err error
+
+		switch name {
+		case configuration.ProviderConsul:
+			provider, err = newConsulProvider(config)
+		case configuration.ProviderNomad:
+			provider, err = newNomadProvider(config)
+		case configuration.ProviderDNS:
+			provider, err = newDNSProvider(config)
+		case configuration.ProviderFile:
+			provider, err = newFileProvider(config)
+		default:
+			err = fmt.Errorf("Unknown provider %s", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		providers = append(providers, &measuredProvider{provider})
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+
+	return &multiProvider{providers: providers}, nil
+}
+
+// measuredProvider records the duration and
+// the errors of the fetches of a provider.
+type measuredProvider struct {
+	Provider
+}
+
+func (p *measuredProvider) FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error) {
+	fetchStart := time.Now()
+
+	services, err := p.Provider.FetchServices(ctx)
+	metrics.ProviderFetchDuration.WithLabelValues(p.Name()).Observe(time.Since(fetchStart).Seconds())
+	if err != nil {
+		metrics.ProviderFetchErrors.WithLabelValues(p.Name()).Inc()
+	}
+
+	return services, err
+}
+
+// isEnabled determines if the tags enable routing for an instance.
+func isEnabled(tags []string, prefix string) bool {
+	for _, tag := range tags {
+		if tag == prefix+".enable=true" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// multiProvider combines the services of multiple providers,
+// instances of services with the same name are merged.
+type multiProvider struct {
+	providers []Provider
+}
+
+func (p *multiProvider) Name() string {
+	names := make([]string, 0, len(p.providers))
+	for _, provider := range p.providers {
+		names = append(names, provider.Name())
+	}
+
+	return strings.Join(names, ",")
+}
+
+func (p *multiProvider) FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error) {
+	result := make(map[string][]*types.ServiceInstance)
+
+	for _, provider := range p.providers {
+		services, err := provider.FetchServices(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", provider.Name(), err)
+		}
+
+		for serviceName, instances := range services {
+			result[serviceName] = append(result[serviceName], instances...)
+		}
+	}
+
+	return result, nil
+}
+
+func (p *multiProvider) Watch(ctx context.Context, notify func()) {
+	var wait sync.WaitGroup
+
+	for _, provider := range p.providers {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			provider.Watch(ctx, notify)
+		}()
+	}
+
+	wait.Wait()
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/golang/glog"
+	capi "github.com/hashicorp/consul/api"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+	"gopkg.in/yaml.v3"
+)
+
+// SnapshotFileName is the name of the snapshot
+// file within a recorded snapshot directory.
+const SnapshotFileName = "services.json"
+
+// readSnapshotFile reads a Json or Yaml snapshot file as Json.
+func readSnapshotFile(fileName string) ([]byte, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch path.Ext(fileName) {
+	case ".json":
+		return content, nil
+	case ".yml", ".yaml":
+		// The snapshot types only have Json field names, so
+		// go through Json to keep the same shape for both.
+		var document any
+		if err = yaml.Unmarshal(content, &document); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(document)
+	default:
+		return nil, fmt.Errorf("Unknown snapshot file extension %s, expected one of .json, .yml, or .yaml", path.Ext(fileName))
+	}
+}
+
+// isCatalogSnapshot determines if the snapshot is in the shape of
+// the Consul Catalog rather than of provider-neutral instances.
+func isCatalogSnapshot(content []byte) (bool, error) {
+	var snapshot map[string][]map[string]json.RawMessage
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return false, err
+	}
+
+	for _, instances := range snapshot {
+		for _, instance := range instances {
+			if _, ok := instance["ServiceTags"]; ok {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// LoadSnapshot loads a snapshot of services from a Json or Yaml file, either
+// in the shape of the Consul Catalog (a map of service name to CatalogService
+// list) or of a map of service name to ServiceInstance list.
+//
+// If fileName is a directory, the snapshot is loaded from SnapshotFileName within it.
+func LoadSnapshot(fileName string, config *configuration.Config) (map[string][]*types.ServiceInstance, error) {
+	if stat, err := os.Stat(fileName); err == nil && stat.IsDir() {
+		fileName = filepath.Join(fileName, SnapshotFileName)
+	}
+
+	content, err := readSnapshotFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	isCatalog, err := isCatalogSnapshot(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if isCatalog {
+		var snapshot map[string][]*capi.CatalogService
+		if err = json.Unmarshal(content, &snapshot); err != nil {
+			return nil, err
+		}
+
+		return CatalogToInstances(snapshot, config), nil
+	}
+
+	var snapshot map[string][]*types.ServiceInstance
+	if err = json.Unmarshal(content, &snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// snapshotProvider serves a recorded snapshot.
+type snapshotProvider struct {
+	services map[string][]*types.ServiceInstance
+}
+
+// NewSnapshotProvider creates a provider that always returns the
+// services of the snapshot, see LoadSnapshot.
+func NewSnapshotProvider(fileName string, config *configuration.Config) (Provider, error) {
+	services, err := LoadSnapshot(fileName, config)
+	if err != nil {
+		return nil, err
+	}
+
+	glog.Infof("Replaying recorded snapshot with %d services from %s.", len(services), fileName)
+
+	return &snapshotProvider{services: services}, nil
+}
+
+// IsSnapshotProvider determines if the provider replays a recorded
+// snapshot, in which case nothing else live should be read either.
+func IsSnapshotProvider(provider Provider) bool {
+	_, ok := provider.(*snapshotProvider)
+
+	return ok
+}
+
+func (p *snapshotProvider) Name() string {
+	return "snapshot"
+}
+
+func (p *snapshotProvider) FetchServices(ctx context.Context) (map[string][]*types.ServiceInstance, error) {
+	return p.services, nil
+}
+
+func (p *snapshotProvider) Watch(ctx context.Context, notify func()) {}
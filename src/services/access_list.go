@@ -0,0 +1,101 @@
+package services
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+const ACL_FILE_EXTENSION = ".acl"
+
+// aclFileContent gets the content of the file of an access list.
+func aclFileContent(addresses []string) string {
+	return strings.Join(addresses, "\n") + "\n"
+}
+
+// aclFilePath gets the path of the file of an access list, files are
+// named after their content so that HAProxy is reloaded when it changes.
+func aclFilePath(addresses []string, config *configuration.Config) string {
+	digest := sha256.Sum256([]byte(aclFileContent(addresses)))
+
+	return filepath.Join(config.AccessLists.Directory, fmt.Sprintf("%x%s", digest[:8], ACL_FILE_EXTENSION))
+}
+
+// aclPattern renders the patterns of an access list, either
+// inline or as a reference to its file if it is too large.
+func aclPattern(addresses []string, config *configuration.Config) string {
+	if len(addresses) > *config.AccessLists.InlineLimit {
+		return "-f " + aclFilePath(addresses, config)
+	}
+
+	return strings.Join(addresses, " ")
+}
+
+// buildAccessLists renders the rules that deny clients on the deny
+// list, or not on the allow list if there is one.
+func buildAccessLists(allowList, denyList []string, config *configuration.Config) string {
+	var result string
+
+	if len(denyList) > 0 {
+		result += fmt.Sprintf("  http-request deny if { src %s }\n", aclPattern(denyList, config))
+	}
+
+	if len(allowList) > 0 {
+		result += fmt.Sprintf("  http-request deny if !{ src %s }\n", aclPattern(allowList, config))
+	}
+
+	return result
+}
+
+// buildClientAddress renders the rules that take the address
+// of clients from the trusted proxies of an entrypoint.
+func buildClientAddress(entryPointConfig *configuration.EntrypointConfig, config *configuration.Config) string {
+	switch entryPointConfig.ClientAddress {
+	case configuration.ClientAddressForwardedFor:
+		return fmt.Sprintf("  http-request set-src hdr_ip(X-Forwarded-For,-1) if { src %s } { req.hdr(X-Forwarded-For) -m found }\n", aclPattern(entryPointConfig.TrustedProxies, config))
+	case configuration.ClientAddressProxyProtocol:
+		return fmt.Sprintf("  tcp-request connection expect-proxy layer4 if { src %s }\n", aclPattern(entryPointConfig.TrustedProxies, config))
+	default:
+		return ""
+	}
+}
+
+// buildEntrypointAccessRules renders the access rules of an entrypoint
+// which must come first in its frontend.
+func buildEntrypointAccessRules(entryPoint string, config *configuration.Config) string {
+	entryPointConfig, ok := config.Entrypoints[entryPoint]
+	if !ok {
+		return ""
+	}
+
+	return buildClientAddress(entryPointConfig, config) + buildAccessLists(entryPointConfig.AllowList, entryPointConfig.DenyList, config)
+}
+
+// BuildACLFiles builds a map of file path to content of the
+// access lists that are too large to be rendered inline.
+func BuildACLFiles(services []*types.Service, config *configuration.Config) map[string]string {
+	lists := make([][]string, 0, 3*len(config.Entrypoints)+2*len(services))
+
+	for _, entryPointConfig := range config.Entrypoints {
+		lists = append(lists, entryPointConfig.AllowList, entryPointConfig.DenyList, entryPointConfig.TrustedProxies)
+	}
+
+	for _, service := range services {
+		lists = append(lists, service.Config.Be.AllowList, service.Config.Be.DenyList)
+	}
+
+	files := make(map[string]string)
+
+	for _, addresses := range slices.DeleteFunc(lists, func(addresses []string) bool {
+		return len(addresses) <= *config.AccessLists.InlineLimit
+	}) {
+		files[aclFilePath(addresses, config)] = aclFileContent(addresses)
+	}
+
+	return files
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// USERLIST_REDACTED_PASSWORD replaces the password hashes of
+// users in the copies of the configuration that are not secret.
+const USERLIST_REDACTED_PASSWORD = "<redacted>"
+
+func validateAuthConfig(auth *types.AuthConfiguration, config *configuration.Config) error {
+	if auth.Userlist == "" {
+		return fmt.Errorf("Auth must specify a userlist.")
+	}
+
+	if _, ok := config.Userlists[auth.Userlist]; !ok {
+		return fmt.Errorf("Unknown userlist %s", auth.Userlist)
+	}
+
+	if strings.ContainsAny(auth.Realm, "\"\\") {
+		return fmt.Errorf("Auth realm must not contain quotes or backslashes, got %s", auth.Realm)
+	}
+
+	return nil
+}
+
+// buildAuth renders the rule that requires the clients
+// of the backend of a service to authenticate.
+func buildAuth(service *types.Service) string {
+	auth := service.Config.Be.Auth
+	if auth == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("  http-request auth realm \"%s\" unless { http_auth(%s) }\n", cmp.Or(auth.Realm, service.ServiceName), auth.Userlist)
+}
+
+// RejectUnavailableUserlists turns the services that require auth against
+// a userlist that could not be loaded into parse errors, so that they are
+// not routed without auth while the other services are.
+func RejectUnavailableUserlists(services []*types.Service, parseErrors []*ParseError, unavailable map[string]error) ([]*types.Service, []*ParseError) {
+	if len(unavailable) == 0 {
+		return services, parseErrors
+	}
+
+	services = slices.DeleteFunc(services, func(service *types.Service) bool {
+		auth := service.Config.Be.Auth
+		if auth == nil {
+			return false
+		}
+
+		err, ok := unavailable[auth.Userlist]
+		if ok {
+			parseErrors = append(parseErrors, newParseError(service.ServiceName, fmt.Errorf("Userlist %s could not be loaded: %w", auth.Userlist, err)))
+		}
+
+		return ok
+	})
+
+	return services, parseErrors
+}
+
+// BuildUserlists renders the userlist sections from a map of
+// userlist name to the map of user name to password hash.
+func BuildUserlists(userlists map[string]map[string]string) string {
+	var result string
+
+	for _, name := range slices.Sorted(maps.Keys(userlists)) {
+		result += fmt.Sprintf("userlist %s\n", name)
+
+		users := userlists[name]
+
+		for _, user := range slices.Sorted(maps.Keys(users)) {
+			result += fmt.Sprintf("  user %s password %s\n", user, users[user])
+		}
+
+		result += "\n"
+	}
+
+	return result
+}
+
+// RedactUserlists replaces the password hashes of the userlists of a
+// rendered configuration, for the copies of it that are logged or served.
+func RedactUserlists(renderedConfiguration string) string {
+	lines := strings.Split(renderedConfiguration, "\n")
+
+	inUserlist := false
+
+	for i, line := range lines {
+		fields := strings.Fields(line)
+
+		// Sections start at the beginning of the line.
+		if len(fields) > 0 && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			inUserlist = fields[0] == "userlist"
+
+			continue
+		}
+
+		if inUserlist && len(fields) >= 4 && fields[0] == "user" && (fields[2] == "password" || fields[2] == "insecure-password") {
+			fields[3] = USERLIST_REDACTED_PASSWORD
+			lines[i] = "  " + strings.Join(fields, " ")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
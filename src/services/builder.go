@@ -32,7 +32,12 @@ func BuildRules(services []*types.Service, config *configuration.Config) map[str
 	entrypointMap := make(map[string]string)
 
 	for entryPoint := range config.Entrypoints {
-		var rules string = ""
+		var rules string = buildEntrypointAccessRules(entryPoint, config)
+
+		// Redirects must come before every use_backend rule.
+		for _, service := range services {
+			rules += buildRedirectsForEntrypoint(entryPoint, service)
+		}
 
 		for _, service := range services {
 			rules += buildRuleForEntrypoint(entryPoint, service)
@@ -77,10 +82,17 @@ func buildBackendForEntrypoint(entryPoint string, service *types.Service, config
 
 	result += "  default-server pool-purge-delay 30s\n"
 
-	if entryPointConfig, ok := config.Entrypoints[entryPoint]; ok {
+	entryPointConfig, ok := config.Entrypoints[entryPoint]
+	if ok {
 		result += entryPointConfig.String()
 	}
 
+	result += buildAccessLists(service.Config.Be.AllowList, service.Config.Be.DenyList, config)
+
+	result += buildRateLimit(service, entryPointConfig)
+
+	result += buildAuth(service)
+
 	if len(service.Config.Be.BlockedPaths) > 0 {
 		result += fmt.Sprintf("  http-request deny if { path %s }\n", strings.Join(service.Config.Fe.BlockedPaths, " "))
 	}
@@ -103,9 +115,20 @@ func buildBackendForEntrypoint(entryPoint string, service *types.Service, config
 		result += fmt.Sprintf("  http-request set-header Host %s\n", service.Config.Be.SetHostHeader)
 	}
 
+	result += buildResponseHeaders(service, config)
+
 	result += fmt.Sprintf("  balance %s\n", service.Config.Be.Balance)
 	result += fmt.Sprintf("  hash-type %s\n", service.Config.Be.HashType)
 
+	if service.Config.Be.Cookie != nil {
+		result += buildCookie(service.Config.Be.Cookie)
+	}
+
+	// Spread the traffic across every backup instead of only the first one.
+	if slices.ContainsFunc(service.Nodes, func(node *types.ServiceNode) bool { return node.Backup }) {
+		result += "  option allbackups\n"
+	}
+
 	var healthCheck *configuration.HealthCheckConfig
 
 	if serviceHealthCheck, ok := config.HealthChecks[service.ServiceName]; ok {
@@ -128,24 +151,100 @@ func buildBackendForEntrypoint(entryPoint string, service *types.Service, config
 
 	result += fmt.Sprintf("  default-server inter %s rise %d fall %d\n", config.ServersConfig.Default.Interval, config.ServersConfig.Default.Rise, config.ServersConfig.PerServer.Fall)
 
+	serverOptions := buildServerOptions(service, config)
+
+	if config.HAProxy.RuntimeAPI.Enabled {
+		result += buildServerSlots(service, serverOptions, config)
+
+		return result
+	}
+
+	for _, node := range service.Nodes {
+		result += fmt.Sprintf("  server %s %s:%d%s%s\n", node.Name, node.Address, node.Port, serverOptions, buildNodeOptions(node, config))
+	}
+
+	return result
+}
+
+func buildServerOptions(service *types.Service, config *configuration.Config) string {
+	var result string
+
+	if service.Config.Protocol == PROTO_HTTPS {
+		if config.TLSBundleFilePath != "" {
+			result += fmt.Sprintf(" ssl verify required ca-file %s", config.TLSBundleFilePath)
+		} else {
+			result += " ssl verify none"
+		}
+	}
+
+	result += fmt.Sprintf(" check inter %s rise %d fall %d", config.ServersConfig.PerServer.Interval, config.ServersConfig.PerServer.Rise, config.ServersConfig.PerServer.Fall)
+
+	if service.Config.Protocol == PROTO_H2C {
+		result += " proto h2"
+	}
+
+	return result
+}
+
+func buildNodeOptions(node *types.ServiceNode, config *configuration.Config) string {
+	var result string
+
+	if node.Weight != DEFAULT_WEIGHT {
+		result += fmt.Sprintf(" weight %d", node.Weight)
+	}
+
+	if node.Backup {
+		result += " backup"
+	}
+
+	if node.Cookie != "" {
+		result += fmt.Sprintf(" cookie %s", node.Cookie)
+	}
+
+	if !node.Disabled {
+		return result
+	}
+
+	if config.Consul.CriticalInstances == configuration.ConsulCriticalInstancesDown {
+		return result + " init-state down"
+	}
+
+	return result + " disabled"
+}
+
+// buildServerSlots renders every slot of the service, nodes are rendered
+// in their assigned slot and free slots are rendered disabled through
+// server-template so they can be filled in through the Runtime API.
+func buildServerSlots(service *types.Service, serverOptions string, config *configuration.Config) string {
+	var result string
+
+	nodesBySlot := make(map[int]*types.ServiceNode, len(service.Nodes))
 	for _, node := range service.Nodes {
-		result += fmt.Sprintf("  server %s %s:%d", node.Name, node.Address, node.Port)
+		nodesBySlot[node.Slot] = node
+	}
+
+	freeSlotsStart := 0
 
-		if service.Config.Protocol == PROTO_HTTPS {
-			if config.TLSBundleFilePath != "" {
-				result += fmt.Sprintf(" ssl verify required ca-file %s", config.TLSBundleFilePath)
-			} else {
-				result += " ssl verify none"
+	for slot := 1; slot <= service.Slots+1; slot++ {
+		node, ok := nodesBySlot[slot]
+
+		if !ok && slot <= service.Slots {
+			if freeSlotsStart == 0 {
+				freeSlotsStart = slot
 			}
+
+			continue
 		}
 
-		result += fmt.Sprintf(" check inter %s rise %d fall %d", config.ServersConfig.PerServer.Interval, config.ServersConfig.PerServer.Rise, config.ServersConfig.PerServer.Fall)
+		if freeSlotsStart != 0 {
+			result += fmt.Sprintf("  server-template %s %d-%d %s%s disabled\n", SLOT_SERVER_PREFIX, freeSlotsStart, slot-1, SLOT_PLACEHOLDER_ADDRESS, serverOptions)
 
-		if service.Config.Protocol == PROTO_H2C {
-			result += " proto h2"
+			freeSlotsStart = 0
 		}
 
-		result += "\n"
+		if ok {
+			result += fmt.Sprintf("  server %s %s:%d%s%s\n", SlotServerName(slot), node.Address, node.Port, serverOptions, buildNodeOptions(node, config))
+		}
 	}
 
 	return result
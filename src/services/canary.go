@@ -0,0 +1,245 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+const (
+	CANARY_NODE_PREFIX = "canary-"
+	MAX_CANARY_WEIGHT  = 100
+)
+
+// canaryTag gets the tag of the canary instances of a service, if any.
+//
+// It is looked up before the labels of the service are selected, as the
+// canary instances usually disagree with the others on the canary weight.
+func canaryTag(serviceInstances []*types.ServiceInstance, prefix string) string {
+	for _, instance := range serviceInstances {
+		labels, _ := instanceLabels(instance, prefix)
+		if tag, ok := lookupLabel(labels, "haproxy.be.canary.tag"); ok && tag != "" {
+			return tag
+		}
+	}
+
+	return ""
+}
+
+// splitCanaryInstances splits the instances of a service
+// between the primary and the canary instances.
+func splitCanaryInstances(serviceInstances []*types.ServiceInstance, tag string) ([]*types.ServiceInstance, []*types.ServiceInstance) {
+	if tag == "" {
+		return serviceInstances, nil
+	}
+
+	var primary, canary []*types.ServiceInstance
+
+	for _, instance := range serviceInstances {
+		if slices.Contains(instance.Tags, tag) {
+			canary = append(canary, instance)
+		} else {
+			primary = append(primary, instance)
+		}
+	}
+
+	return primary, canary
+}
+
+// canaryWeight gets the canary weight set by the canary
+// instances themselves, or nil if they do not set one.
+func canaryWeight(canaryInstances []*types.ServiceInstance, config *configuration.Config) (*int, error) {
+	labels, _, err := selectLabels(canaryInstances, config)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := lookupLabel(labels, "haproxy.be.canary.weight")
+	if !ok {
+		return nil, nil
+	}
+
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 0 || weight > MAX_CANARY_WEIGHT {
+		return nil, fmt.Errorf("Invalid canary weight %q, expected a percentage between 0 and %d", value, MAX_CANARY_WEIGHT)
+	}
+
+	return &weight, nil
+}
+
+// addCanaryNodes adds the nodes of the canary instances to the service
+// and splits the weights of the nodes between the primary and the canary
+// nodes according to the canary weight.
+//
+// The canary weight set by the canary instances takes precedence over the
+// one of the service, so that it can be changed without redeploying it.
+func addCanaryNodes(service *types.Service, canaryInstances []*types.ServiceInstance, config *configuration.Config) []error {
+	var warnings []error
+
+	percent := 0
+	if canary := service.Config.Be.Canary; canary != nil && canary.Weight != nil {
+		percent = *canary.Weight
+	}
+
+	weight, err := canaryWeight(canaryInstances, config)
+	if err != nil {
+		warnings = append(warnings, fmt.Errorf("Ignoring the canary weight of the canary instances: %w", err))
+	} else if weight != nil {
+		percent = *weight
+	}
+
+	for _, serviceNode := range buildServiceNodes(service.ServiceName, canaryInstances, service.Config, config) {
+		serviceNode.Name = CANARY_NODE_PREFIX + serviceNode.Name
+		serviceNode.Canary = true
+
+		service.Nodes = append(service.Nodes, serviceNode)
+	}
+
+	splitCanaryWeights(service.Nodes, percent)
+
+	return warnings
+}
+
+// routingLabels are the labels that route traffic
+// to a service directly, bypassing any primary service.
+var routingLabels = []string{"haproxy.fe.fqdn", "haproxy.fe.entrypoints"}
+
+// hasRoutingLabels determines if any instance of a service has routing
+// labels, in which case it is routed on its own and cannot be a canary.
+func hasRoutingLabels(serviceInstances []*types.ServiceInstance, prefix string) bool {
+	for _, instance := range serviceInstances {
+		labels, _ := instanceLabels(instance, prefix)
+
+		for _, key := range routingLabels {
+			if _, ok := lookupLabel(labels, key); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// attachCanaryServices adds the nodes of canary services to the services
+// that split their traffic with them. Canary services are only routed
+// through their primary service, so they are removed along with their
+// parse errors.
+//
+// A service with routing labels of its own cannot be a canary, so that a
+// service cannot take over the traffic of another by naming it as its
+// canary. The service naming it is skipped instead.
+func attachCanaryServices(services []*types.Service, parseErrors []*ParseError, serviceInstances map[string][]*types.ServiceInstance, config *configuration.Config) ([]*types.Service, []*ParseError) {
+	canaryServices := make(map[string]bool)
+
+	for _, service := range services {
+		if canary := service.Config.Be.Canary; canary != nil && canary.Service != "" {
+			canaryServices[canary.Service] = !hasRoutingLabels(serviceInstances[canary.Service], config.Prefix)
+		}
+	}
+
+	if len(canaryServices) == 0 {
+		return services, parseErrors
+	}
+
+	parseErrors = slices.DeleteFunc(parseErrors, func(parseError *ParseError) bool {
+		return canaryServices[parseError.ServiceName]
+	})
+
+	services = slices.DeleteFunc(services, func(service *types.Service) bool {
+		if canaryServices[service.ServiceName] {
+			return true
+		}
+
+		canary := service.Config.Be.Canary
+		if canary == nil || canary.Service == "" || canaryServices[canary.Service] {
+			return false
+		}
+
+		parseErrors = append(parseErrors, newParseError(service.ServiceName, fmt.Errorf("Canary service %s has FQDN or entrypoints labels of its own, only a service without them can be a canary.", canary.Service)))
+
+		return true
+	})
+
+	for _, service := range services {
+		canary := service.Config.Be.Canary
+		if canary == nil || canary.Service == "" {
+			continue
+		}
+
+		canaryInstances := serviceInstances[canary.Service]
+		if len(canaryInstances) == 0 {
+			parseErrors = append(parseErrors, newParseWarning(service.ServiceName, fmt.Errorf("Canary service %s has no instances, sending all traffic to the service", canary.Service)))
+
+			continue
+		}
+
+		for _, warning := range addCanaryNodes(service, canaryInstances, config) {
+			parseErrors = append(parseErrors, newParseWarning(service.ServiceName, warning))
+		}
+
+		sortServiceNodes(service.Nodes)
+	}
+
+	return services, parseErrors
+}
+
+// splitCanaryWeights scales the weights of the nodes so that the canary
+// nodes receive percent of the traffic and the primary nodes the rest,
+// each node in proportion to its own weight within its group.
+//
+// Weights are scaled so that the largest is MAX_WEIGHT, to keep
+// as much precision as possible. Backup nodes are left untouched.
+func splitCanaryWeights(serviceNodes []*types.ServiceNode, percent int) {
+	var primaryTotal, canaryTotal int
+
+	for _, serviceNode := range serviceNodes {
+		if serviceNode.Backup || serviceNode.Disabled {
+			continue
+		}
+
+		if serviceNode.Canary {
+			canaryTotal += serviceNode.Weight
+		} else {
+			primaryTotal += serviceNode.Weight
+		}
+	}
+
+	// Without any available node on one side, the other
+	// side receives all of the traffic regardless.
+	if primaryTotal == 0 || canaryTotal == 0 {
+		return
+	}
+
+	shares := make(map[*types.ServiceNode]float64, len(serviceNodes))
+
+	var maxShare float64
+
+	for _, serviceNode := range serviceNodes {
+		if serviceNode.Backup {
+			continue
+		}
+
+		share := float64(serviceNode.Weight) * float64(MAX_CANARY_WEIGHT-percent) / float64(primaryTotal)
+		if serviceNode.Canary {
+			share = float64(serviceNode.Weight) * float64(percent) / float64(canaryTotal)
+		}
+
+		shares[serviceNode] = share
+		maxShare = max(maxShare, share)
+	}
+
+	for serviceNode, share := range shares {
+		if share == 0 {
+			serviceNode.Weight = 0
+
+			continue
+		}
+
+		// A node that must receive traffic keeps a weight of at least 1.
+		serviceNode.Weight = max(int(math.Round(share/maxShare*MAX_WEIGHT)), 1)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	paerser "github.com/traefik/paerser/types"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+const (
+	COOKIE_INSERT  = "insert"
+	COOKIE_REWRITE = "rewrite"
+	COOKIE_PREFIX  = "prefix"
+
+	DEFAULT_COOKIE_NAME = "SERVERID"
+)
+
+func validateCookieConfig(config *types.CookieConfiguration) error {
+	if config.Name == "" {
+		config.Name = DEFAULT_COOKIE_NAME
+	}
+
+	if config.Mode == "" {
+		config.Mode = COOKIE_INSERT
+	}
+
+	config.Mode = strings.ToLower(config.Mode)
+
+	if config.Mode != COOKIE_INSERT && config.Mode != COOKIE_REWRITE && config.Mode != COOKIE_PREFIX {
+		return fmt.Errorf("Invalid cookie mode specified, expected one of insert, rewrite, or prefix, got %s", config.Mode)
+	}
+
+	for _, duration := range []paerser.Duration{config.MaxIdle, config.MaxLife} {
+		if duration != 0 && time.Duration(duration) < time.Millisecond {
+			return fmt.Errorf("Cookie max idle and max life must be at least 1ms, got %s", duration)
+		}
+	}
+
+	if config.Mode != COOKIE_INSERT && (config.MaxIdle != 0 || config.MaxLife != 0) {
+		return fmt.Errorf("Cookie max idle and max life are only supported in insert mode.")
+	}
+
+	return nil
+}
+
+// nodeCookie derives the persistence cookie value of a node from its
+// instance, so that it survives reloads and the reordering of nodes.
+func nodeCookie(instance *types.ServiceInstance) string {
+	hash := fnv.New64a()
+
+	fmt.Fprintf(hash, "%s\x00%s\x00%s", instance.Datacenter, instance.Name, instanceID(instance))
+
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// buildCookie renders the cookie directive of a backend.
+func buildCookie(config *types.CookieConfiguration) string {
+	result := fmt.Sprintf("  cookie %s %s", config.Name, config.Mode)
+
+	if config.NoCache {
+		result += " nocache"
+	}
+
+	if config.HttpOnly {
+		result += " httponly"
+	}
+
+	if config.Secure {
+		result += " secure"
+	}
+
+	if config.MaxIdle > 0 {
+		result += fmt.Sprintf(" maxidle %ds", int64((time.Duration(config.MaxIdle)+time.Second-1)/time.Second))
+	}
+
+	if config.MaxLife > 0 {
+		result += fmt.Sprintf(" maxlife %ds", int64((time.Duration(config.MaxLife)+time.Second-1)/time.Second))
+	}
+
+	return result + "\n"
+}
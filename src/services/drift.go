@@ -0,0 +1,187 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// LabelDriftGroup is a group of instances of a
+// service that agree on their labels.
+type LabelDriftGroup struct {
+	// Selected determines if the labels of this
+	// group were used to render the service.
+	Selected bool `json:"selected"`
+
+	// Instances are the IDs of the instances in this group.
+	Instances []string `json:"instances"`
+
+	// Labels are the labels of this group that
+	// are not the same in every other group.
+	Labels map[string]string `json:"labels"`
+}
+
+// LabelDrift describes the instances of a service
+// that disagree on their labels.
+type LabelDrift struct {
+	// Policy is the policy used to resolve the drift.
+	Policy string `json:"policy"`
+
+	// Groups are the groups of instances that agree on
+	// their labels, the selected group comes first.
+	Groups []*LabelDriftGroup `json:"groups"`
+}
+
+// LabelDriftError is raised when the instances
+// of a service disagree on their labels.
+type LabelDriftError struct {
+	Drift *LabelDrift
+}
+
+func (e *LabelDriftError) Error() string {
+	if e.Drift.Policy == configuration.LabelDriftPolicyFail {
+		return fmt.Sprintf("Instances disagree on their labels in %d groups.", len(e.Drift.Groups))
+	}
+
+	return fmt.Sprintf("Instances disagree on their labels in %d groups, using the labels of %s (%s policy).", len(e.Drift.Groups), strings.Join(e.Drift.Groups[0].Instances, ", "), e.Drift.Policy)
+}
+
+// labelGroup is the instances that share the same labels.
+type labelGroup struct {
+	key         string
+	labels      map[string]string
+	warnings    []error
+	instances   []*types.ServiceInstance
+	modifyIndex uint64
+}
+
+// labelsKey gets a canonical key of the labels,
+// labels are case insensitive.
+func labelsKey(labels map[string]string) string {
+	lines := make([]string, 0, len(labels))
+	for key, value := range labels {
+		lines = append(lines, strings.ToLower(key)+"="+value)
+	}
+
+	slices.Sort(lines)
+
+	return strings.Join(lines, "\n")
+}
+
+func instanceID(instance *types.ServiceInstance) string {
+	return cmp.Or(instance.ID, instance.Name)
+}
+
+// selectLabels selects the labels of the service among its instances,
+// preferring local instances. If the instances disagree, the drift
+// is resolved according to config.LabelDriftPolicy and reported as
+// a LabelDriftError within the warnings, or as the error.
+func selectLabels(serviceInstances []*types.ServiceInstance, config *configuration.Config) (map[string]string, []error, error) {
+	candidates := slices.DeleteFunc(slices.Clone(serviceInstances), func(instance *types.ServiceInstance) bool {
+		return instance.Remote
+	})
+
+	if len(candidates) == 0 {
+		candidates = serviceInstances
+	}
+
+	groupsByKey := make(map[string]*labelGroup)
+
+	var groups []*labelGroup
+
+	for _, instance := range candidates {
+		labels, warnings := instanceLabels(instance, config.Prefix)
+		key := labelsKey(labels)
+
+		group, ok := groupsByKey[key]
+		if !ok {
+			group = &labelGroup{key: key, labels: labels, warnings: warnings}
+			groupsByKey[key] = group
+			groups = append(groups, group)
+		}
+
+		group.instances = append(group.instances, instance)
+		group.modifyIndex = max(group.modifyIndex, instance.ModifyIndex)
+	}
+
+	if len(groups) == 1 {
+		return groups[0].labels, groups[0].warnings, nil
+	}
+
+	slices.SortFunc(groups, func(a, b *labelGroup) int {
+		if config.LabelDriftPolicy == configuration.LabelDriftPolicyMajority {
+			if order := cmp.Compare(len(b.instances), len(a.instances)); order != 0 {
+				return order
+			}
+		}
+
+		return cmp.Or(
+			cmp.Compare(b.modifyIndex, a.modifyIndex),
+			strings.Compare(a.key, b.key),
+		)
+	})
+
+	driftErr := &LabelDriftError{Drift: newLabelDrift(groups, config.LabelDriftPolicy)}
+
+	if config.LabelDriftPolicy == configuration.LabelDriftPolicyFail {
+		return nil, nil, driftErr
+	}
+
+	return groups[0].labels, append([]error{driftErr}, groups[0].warnings...), nil
+}
+
+func newLabelDrift(groups []*labelGroup, policy string) *LabelDrift {
+	drift := &LabelDrift{Policy: policy}
+
+	// Labels are only reported if they differ in at least one group.
+	differingLabels := make(map[string]bool)
+
+	for _, group := range groups {
+		for key, value := range group.labels {
+			for _, other := range groups {
+				if otherValue, ok := lookupLabel(other.labels, key); !ok || otherValue != value {
+					differingLabels[strings.ToLower(key)] = true
+				}
+			}
+		}
+	}
+
+	for i, group := range groups {
+		driftGroup := &LabelDriftGroup{
+			Selected: i == 0 && policy != configuration.LabelDriftPolicyFail,
+			Labels:   make(map[string]string),
+		}
+
+		for _, instance := range group.instances {
+			driftGroup.Instances = append(driftGroup.Instances, instanceID(instance))
+		}
+
+		slices.Sort(driftGroup.Instances)
+
+		for _, key := range slices.Sorted(maps.Keys(group.labels)) {
+			if differingLabels[strings.ToLower(key)] {
+				driftGroup.Labels[key] = group.labels[key]
+			}
+		}
+
+		drift.Groups = append(drift.Groups, driftGroup)
+	}
+
+	return drift
+}
+
+// lookupLabel looks a label up case insensitively.
+func lookupLabel(labels map[string]string, key string) (string, bool) {
+	for labelKey, value := range labels {
+		if strings.EqualFold(labelKey, key) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ParseError is an error raised when parsing the
+// labels of a single service.
+//
+// Services with a ParseError are skipped, unless it
+// is a warning, and every other service is still rendered.
+type ParseError struct {
+	// ServiceName is the name of the service.
+	ServiceName string `json:"serviceName"`
+
+	// Message is the error message.
+	Message string `json:"message"`
+
+	// Warning determines if the service was still
+	// rendered despite the error.
+	Warning bool `json:"warning"`
+
+	// Drift describes the instances that disagree
+	// on their labels, if that is the error.
+	Drift *LabelDrift `json:"drift,omitempty"`
+
+	// Time is when the error occurred.
+	Time time.Time `json:"time"`
+}
+
+func newParseError(serviceName string, err error) *ParseError {
+	parseError := &ParseError{
+		ServiceName: serviceName,
+		Message:     err.Error(),
+		Time:        time.Now(),
+	}
+
+	var driftErr *LabelDriftError
+	if errors.As(err, &driftErr) {
+		parseError.Drift = driftErr.Drift
+	}
+
+	return parseError
+}
+
+func newParseWarning(serviceName string, err error) *ParseError {
+	parseError := newParseError(serviceName, err)
+	parseError.Warning = true
+
+	return parseError
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("service %s: %s", e.ServiceName, e.Message)
+}
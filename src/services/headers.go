@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+func validateResponseHeadersConfig(be *types.BackendConfiguration, config *configuration.Config) error {
+	for _, preset := range be.HeaderPresets {
+		if _, ok := config.ResponseHeaderPresets[preset]; !ok {
+			return fmt.Errorf("Unknown header preset %s", preset)
+		}
+	}
+
+	for _, headers := range []map[string]string{be.SetResponseHeaders, be.AddResponseHeaders} {
+		for header, value := range headers {
+			if !configuration.ValidHeaderName(header) {
+				return fmt.Errorf("Invalid response header name %q", header)
+			}
+
+			if !configuration.ValidHeaderValue(value) {
+				return fmt.Errorf("Invalid value %q of response header %s, control characters are not allowed", value, header)
+			}
+		}
+	}
+
+	for _, header := range be.DelResponseHeaders {
+		if !configuration.ValidHeaderName(header) {
+			return fmt.Errorf("Invalid response header name %q", header)
+		}
+	}
+
+	return nil
+}
+
+// buildResponseHeaders renders the response header rules of the
+// backend of a service. Presets come first, in order, so that the
+// headers set by the labels take precedence.
+func buildResponseHeaders(service *types.Service, config *configuration.Config) string {
+	var result string
+
+	for _, preset := range service.Config.Be.HeaderPresets {
+		headers := config.ResponseHeaderPresets[preset]
+
+		for _, header := range slices.Sorted(maps.Keys(headers)) {
+			result += fmt.Sprintf("  http-response set-header %s %s\n", header, configuration.QuoteValue(headers[header]))
+		}
+	}
+
+	for _, header := range slices.Sorted(maps.Keys(service.Config.Be.SetResponseHeaders)) {
+		result += fmt.Sprintf("  http-response set-header %s %s\n", header, configuration.QuoteValue(service.Config.Be.SetResponseHeaders[header]))
+	}
+
+	for _, header := range slices.Sorted(maps.Keys(service.Config.Be.AddResponseHeaders)) {
+		result += fmt.Sprintf("  http-response add-header %s %s\n", header, configuration.QuoteValue(service.Config.Be.AddResponseHeaders[header]))
+	}
+
+	for _, header := range service.Config.Be.DelResponseHeaders {
+		result += fmt.Sprintf("  http-response del-header %s\n", header)
+	}
+
+	return result
+}
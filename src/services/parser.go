@@ -1,10 +1,15 @@
 package services
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strings"
 
-	capi "github.com/hashicorp/consul/api"
+	"github.com/golang/glog"
 	"github.com/traefik/paerser/parser"
 	"github.rbx.com/roblox/roblox-load-balancer/configuration"
 	"github.rbx.com/roblox/roblox-load-balancer/services/types"
@@ -17,6 +22,9 @@ const (
 
 	ALG_RR          = "roundrobin"
 	HASH_CONSISTENT = "consistent"
+
+	DEFAULT_WEIGHT = 1
+	MAX_WEIGHT     = 256
 )
 
 func validateLabelsConfig(config *types.ServiceConfig, entryPoints map[string]*configuration.EntrypointConfig) error {
@@ -46,14 +54,48 @@ func validateLabelsConfig(config *types.ServiceConfig, entryPoints map[string]*c
 		config.Be.HashType = HASH_CONSISTENT
 	}
 
+	if config.Be.Canary != nil {
+		if config.Be.Canary.Service != "" && config.Be.Canary.Tag != "" {
+			return fmt.Errorf("Canary must specify either a service or a tag, not both.")
+		}
+
+		if weight := config.Be.Canary.Weight; weight != nil && (*weight < 0 || *weight > MAX_CANARY_WEIGHT) {
+			return fmt.Errorf("Invalid canary weight %d, expected a percentage between 0 and %d", *weight, MAX_CANARY_WEIGHT)
+		}
+	}
+
+	if config.Be.Cookie != nil {
+		if err := validateCookieConfig(config.Be.Cookie); err != nil {
+			return err
+		}
+	}
+
+	if config.Be.RateLimit != nil {
+		if err := validateRateLimitConfig(config.Be.RateLimit); err != nil {
+			return err
+		}
+	}
+
+	if err := configuration.ValidateAddresses(config.Be.AllowList); err != nil {
+		return fmt.Errorf("Allow list has an %w", err)
+	}
+
+	if err := configuration.ValidateAddresses(config.Be.DenyList); err != nil {
+		return fmt.Errorf("Deny list has an %w", err)
+	}
+
+	if config.Fe.Redirect != nil {
+		if err := validateRedirectConfig(config.Fe.Redirect, config.Fe.Fqdn); err != nil {
+			return err
+		}
+	}
+
 	if len(config.Fe.Fqdn) == 0 {
 		return fmt.Errorf("Service must specify at least one FQDN.")
 	}
 
 	if len(config.Fe.EntryPoints) == 0 {
-		for entryPoint := range entryPoints {
-			config.Fe.EntryPoints = append(config.Fe.EntryPoints, entryPoint)
-		}
+		config.Fe.EntryPoints = slices.Sorted(maps.Keys(entryPoints))
 	}
 
 	for _, entryPoint := range config.Fe.EntryPoints {
@@ -65,51 +107,279 @@ func validateLabelsConfig(config *types.ServiceConfig, entryPoints map[string]*c
 	return nil
 }
 
-// ParseServicesFromConsul parses services from Consul.
-func ParseServicesFromConsul(serviceNodes map[string][]*capi.CatalogService, config *configuration.Config) ([]*types.Service, error) {
-	services := make([]*types.Service, 0, len(serviceNodes))
+// ParseServices parses services from the instances
+// fetched by a discovery provider.
+//
+// Services that fail to parse are skipped and reported
+// in the returned list of errors, along with warnings
+// for the services that were parsed.
+func ParseServices(serviceInstances map[string][]*types.ServiceInstance, config *configuration.Config) ([]*types.Service, []*ParseError) {
+	services := make([]*types.Service, 0, len(serviceInstances))
+
+	var parseErrors []*ParseError
 
-	for serviceName, serviceInstances := range serviceNodes {
-		service, err := parseServiceFromConsul(serviceName, serviceInstances, config)
+	for serviceName, instances := range serviceInstances {
+		service, warnings, err := parseService(serviceName, instances, config)
 		if err != nil {
-			return nil, err
+			parseErrors = append(parseErrors, newParseError(serviceName, err))
+
+			continue
+		}
+
+		for _, warning := range warnings {
+			parseErrors = append(parseErrors, newParseWarning(serviceName, warning))
 		}
 
 		services = append(services, service)
 	}
 
-	return services, nil
+	services, parseErrors = attachCanaryServices(services, parseErrors, serviceInstances, config)
+
+	slices.SortFunc(services, func(a, b *types.Service) int {
+		return strings.Compare(a.ServiceName, b.ServiceName)
+	})
+
+	slices.SortStableFunc(parseErrors, func(a, b *ParseError) int {
+		return strings.Compare(a.ServiceName, b.ServiceName)
+	})
+
+	return services, parseErrors
+}
+
+// failoverDatacenter picks the remote datacenter to fail over to, the
+// first in order of preference with at least one healthy instance.
+func failoverDatacenter(serviceInstances []*types.ServiceInstance, serviceConfig *types.ServiceConfig, config *configuration.Config) string {
+	failover := *config.Consul.CrossDatacenterFailover
+	if serviceConfig.Be.Failover != nil {
+		failover = *serviceConfig.Be.Failover
+	}
+
+	if !failover {
+		return ""
+	}
+
+	datacenters := serviceConfig.Be.Datacenters
+	if len(datacenters) == 0 {
+		datacenters = config.Consul.Datacenters
+	}
+
+	for _, datacenter := range datacenters {
+		for _, instance := range serviceInstances {
+			if instance.Remote && instance.Datacenter == datacenter && !instance.Unhealthy {
+				return datacenter
+			}
+		}
+	}
+
+	return ""
 }
 
-func parseServiceFromConsul(serviceName string, serviceInstances []*capi.CatalogService, config *configuration.Config) (*types.Service, error) {
+func parseService(serviceName string, serviceInstances []*types.ServiceInstance, config *configuration.Config) (*types.Service, []error, error) {
+	if len(serviceInstances) == 0 {
+		return nil, nil, fmt.Errorf("Service has no instances.")
+	}
+
+	discoveryWarnings, err := discoveryErrors(serviceInstances)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	service := &types.Service{
 		ServiceName: serviceName,
-		Nodes:       make([]*types.ServiceNode, 0, len(serviceInstances)),
 		Config:      &types.ServiceConfig{},
 	}
 
-	for _, entry := range serviceInstances {
+	// Labels are selected among the primary instances only, unless
+	// there are none, as canary instances may carry different ones.
+	primaryInstances, canaryInstances := splitCanaryInstances(serviceInstances, canaryTag(serviceInstances, config.Prefix))
+
+	labelInstances := primaryInstances
+	if len(labelInstances) == 0 {
+		labelInstances = canaryInstances
+	}
+
+	labels, warnings, err := selectLabels(labelInstances, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := parser.Decode(labels, service.Config, "haproxy"); err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateLabelsConfig(service.Config, config.Entrypoints); err != nil {
+		return nil, nil, err
+	}
+
+	if canary := service.Config.Be.Canary; canary != nil && canary.Service == serviceName {
+		return nil, nil, fmt.Errorf("Service cannot be its own canary service.")
+	}
+
+	if service.Config.Be.Auth != nil {
+		if err := validateAuthConfig(service.Config.Be.Auth, config); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := validateResponseHeadersConfig(service.Config.Be, config); err != nil {
+		return nil, nil, err
+	}
+
+	service.Nodes = buildServiceNodes(serviceName, primaryInstances, service.Config, config)
+
+	if len(canaryInstances) != 0 {
+		warnings = append(warnings, addCanaryNodes(service, canaryInstances, config)...)
+	}
+
+	sortServiceNodes(service.Nodes)
+
+	return service, append(discoveryWarnings, warnings...), nil
+}
+
+// discoveryErrors gets the distinct discovery warnings of the instances
+// of a service, or the first discovery error if any.
+func discoveryErrors(serviceInstances []*types.ServiceInstance) ([]error, error) {
+	var warnings []error
+
+	seen := make(map[string]bool)
+
+	for _, instance := range serviceInstances {
+		if instance.Error != "" {
+			return nil, errors.New(instance.Error)
+		}
+
+		if instance.Warning != "" && !seen[instance.Warning] {
+			seen[instance.Warning] = true
+			warnings = append(warnings, errors.New(instance.Warning))
+		}
+	}
+
+	return warnings, nil
+}
+
+// nodeName gets the server name of an instance, qualified with its
+// datacenter if it is remote, as nodes of different datacenters may
+// share their name and HAProxy requires unique server names.
+func nodeName(instance *types.ServiceInstance) string {
+	if instance.Remote {
+		return instance.Name + "." + instance.Datacenter
+	}
+
+	return instance.Name
+}
+
+// buildServiceNodes builds the nodes of a service from its instances.
+func buildServiceNodes(serviceName string, serviceInstances []*types.ServiceInstance, serviceConfig *types.ServiceConfig, config *configuration.Config) []*types.ServiceNode {
+	serviceNodes := make([]*types.ServiceNode, 0, len(serviceInstances))
+
+	backupDatacenter := failoverDatacenter(serviceInstances, serviceConfig, config)
+
+	weightPercents := make(map[*types.ServiceNode]int)
+
+	for _, instance := range serviceInstances {
+		if instance.Remote && instance.Datacenter != backupDatacenter {
+			continue
+		}
+
+		// Placeholders of providers have nothing to route to.
+		if instance.Address == "" {
+			continue
+		}
+
 		serviceNode := &types.ServiceNode{
-			Name:    entry.Node,
-			Address: entry.Address,
-			Port:    entry.ServicePort,
+			Name:       nodeName(instance),
+			Address:    instance.Address,
+			Port:       instance.Port,
+			Weight:     DEFAULT_WEIGHT,
+			Datacenter: instance.Datacenter,
+			Backup:     instance.Remote,
+		}
+
+		if serviceConfig.Be.Cookie != nil {
+			serviceNode.Cookie = nodeCookie(instance)
+		}
+
+		if instance.Weight != nil {
+			serviceNode.Weight = min(max(*instance.Weight, 0), MAX_WEIGHT)
+		}
+
+		if instance.WeightPercent != nil {
+			weightPercents[serviceNode] = min(max(*instance.WeightPercent, 0), 100)
 		}
 
-		if externalSource, ok := entry.ServiceMeta["external-source"]; ok && externalSource == "nomad" {
-			serviceNode.Name = strings.Split(entry.ServiceID, "-")[2] // The short ALLOC id
+		if instance.Unhealthy {
+			if config.Consul.CriticalInstances == configuration.ConsulCriticalInstancesOmit {
+				glog.V(100).Infof("Omitting unhealthy instance %s of service %s.", instance.ID, serviceName)
+
+				continue
+			}
+
+			serviceNode.Disabled = true
 		}
 
-		service.Nodes = append(service.Nodes, serviceNode)
+		serviceNodes = append(serviceNodes, serviceNode)
 	}
 
-	labels := tagsToNeutralLabels(serviceInstances[0].ServiceTags, config.Prefix)
-	if err := parser.Decode(labels, service.Config, "haproxy"); err != nil {
-		return nil, err
+	reduceWeights(serviceNodes, weightPercents)
+
+	return serviceNodes
+}
+
+// reduceWeights reduces the weights of the nodes that only keep a
+// percentage of their weight. Weights are first scaled so that the
+// largest is MAX_WEIGHT, so that nodes with the default weight can
+// be reduced too.
+func reduceWeights(serviceNodes []*types.ServiceNode, weightPercents map[*types.ServiceNode]int) {
+	if len(weightPercents) == 0 {
+		return
 	}
 
-	if err := validateLabelsConfig(service.Config, config.Entrypoints); err != nil {
-		return nil, err
+	maxWeight := 0
+	for _, serviceNode := range serviceNodes {
+		maxWeight = max(maxWeight, serviceNode.Weight)
+	}
+
+	if maxWeight == 0 {
+		return
+	}
+
+	for _, serviceNode := range serviceNodes {
+		weight := float64(serviceNode.Weight) * MAX_WEIGHT / float64(maxWeight)
+
+		if percent, ok := weightPercents[serviceNode]; ok {
+			weight = weight * float64(percent) / 100
+		}
+
+		serviceNode.Weight = int(math.Round(weight))
+
+		// A node that must receive traffic keeps a weight of at least 1.
+		if serviceNode.Weight == 0 && weight > 0 {
+			serviceNode.Weight = 1
+		}
 	}
+}
 
-	return service, nil
+// sortServiceNodes sorts the nodes of a service, local
+// nodes come first, followed by canaries then backups.
+func sortServiceNodes(serviceNodes []*types.ServiceNode) {
+	slices.SortFunc(serviceNodes, func(a, b *types.ServiceNode) int {
+		return cmp.Or(
+			compareBool(a.Backup, b.Backup),
+			compareBool(a.Canary, b.Canary),
+			strings.Compare(a.Name, b.Name),
+			strings.Compare(a.Address, b.Address),
+			cmp.Compare(a.Port, b.Port),
+		)
+	})
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
 }
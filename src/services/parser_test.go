@@ -0,0 +1,284 @@
+package services_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/flags"
+	"github.rbx.com/roblox/roblox-load-balancer/providers"
+	"github.rbx.com/roblox/roblox-load-balancer/services"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// newTestConfig parses a Yaml static configuration, with defaults
+// applied, along with an empty template.
+func newTestConfig(t *testing.T, content string) *configuration.Config {
+	t.Helper()
+
+	directory := t.TempDir()
+
+	templateFilePath := filepath.Join(directory, "haproxy.cfg.tmpl")
+	if err := os.WriteFile(templateFilePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content = "template_file_path: " + templateFilePath + "\n" +
+		"output_file_path: " + filepath.Join(directory, "haproxy.cfg") + "\n" +
+		"haproxy:\n  path: /bin/true\n" +
+		content
+
+	configurationFilePath := filepath.Join(directory, "config.yaml")
+	if err := os.WriteFile(configurationFilePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := *flags.ConfigurationFilePath
+	*flags.ConfigurationFilePath = configurationFilePath
+	t.Cleanup(func() { *flags.ConfigurationFilePath = previous })
+
+	config, err := configuration.ParseConfiguration()
+	if err != nil {
+		t.Fatalf("Got error when parsing the configuration: %v", err)
+	}
+
+	return config
+}
+
+// renderBackends parses the instances of services
+// and renders their backends on every entrypoint.
+func renderBackends(t *testing.T, instances map[string][]*types.ServiceInstance, config *configuration.Config) string {
+	t.Helper()
+
+	svcs, parseErrors := services.ParseServices(instances, config)
+	for _, parseError := range parseErrors {
+		if !parseError.Warning {
+			t.Fatalf("Got parse error: %v", parseError)
+		}
+	}
+
+	var result strings.Builder
+	for _, backends := range services.BuildBackends(svcs, config) {
+		result.WriteString(backends)
+	}
+
+	return result.String()
+}
+
+// serverLine finds the server line of a node in rendered backends.
+func serverLine(t *testing.T, backends, name string) string {
+	t.Helper()
+
+	for _, line := range strings.Split(backends, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "server "+name+" ") {
+			return line
+		}
+	}
+
+	t.Fatalf("Found no server %s in:\n%s", name, backends)
+
+	return ""
+}
+
+func catalogEntry(node, datacenter, status string) *capi.CatalogService {
+	return &capi.CatalogService{
+		Node:           node,
+		Address:        "10.0.0.1",
+		Datacenter:     datacenter,
+		ServiceID:      "web-" + node,
+		ServiceName:    "web",
+		ServiceTags:    []string{"haproxy.enable=true", "haproxy.fe.fqdn=web.example.com"},
+		ServicePort:    80,
+		ServiceWeights: capi.Weights{Passing: 1, Warning: 1},
+		Checks:         capi.HealthChecks{{Status: status}},
+	}
+}
+
+func TestWarningInstancesAreDownWeighted(t *testing.T) {
+	tests := []struct {
+		name          string
+		percent       string
+		passingWeight string
+		warningWeight string
+	}{
+		{name: "default percent", percent: "", passingWeight: " weight 256", warningWeight: " weight 128"},
+		{name: "drained", percent: "0", passingWeight: " weight 256", warningWeight: " weight 0"},
+		{name: "kept", percent: "100", passingWeight: " weight 256", warningWeight: " weight 256"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content := "entrypoints:\n  http: {}\nconsul:\n  health_mode: warning\n"
+			if test.percent != "" {
+				content += "  warning_weight_percent: " + test.percent + "\n"
+			}
+
+			config := newTestConfig(t, content)
+
+			instances := providers.CatalogToInstances(map[string][]*capi.CatalogService{
+				"web": {
+					catalogEntry("passing", "dc1", capi.HealthPassing),
+					catalogEntry("warning", "dc1", capi.HealthWarning),
+				},
+			}, config)
+
+			backends := renderBackends(t, instances, config)
+
+			if line := serverLine(t, backends, "passing"); !strings.Contains(line, test.passingWeight) {
+				t.Errorf("Expected the passing server to have%s, got %q", test.passingWeight, line)
+			}
+
+			if line := serverLine(t, backends, "warning"); !strings.Contains(line, test.warningWeight) {
+				t.Errorf("Expected the warning server to have%s, got %q", test.warningWeight, line)
+			}
+		})
+	}
+}
+
+func TestPassingInstancesKeepTheirWeight(t *testing.T) {
+	config := newTestConfig(t, "entrypoints:\n  http: {}\nconsul:\n  health_mode: warning\n")
+
+	instances := providers.CatalogToInstances(map[string][]*capi.CatalogService{
+		"web": {
+			catalogEntry("a", "dc1", capi.HealthPassing),
+			catalogEntry("b", "dc1", capi.HealthPassing),
+		},
+	}, config)
+
+	backends := renderBackends(t, instances, config)
+
+	for _, name := range []string{"a", "b"} {
+		if line := serverLine(t, backends, name); strings.Contains(line, " weight ") {
+			t.Errorf("Expected server %s to keep the default weight, got %q", name, line)
+		}
+	}
+}
+
+func taggedInstance(serviceName, name string, tags ...string) *types.ServiceInstance {
+	return &types.ServiceInstance{
+		ID:          serviceName + "-" + name,
+		ServiceName: serviceName,
+		Name:        name,
+		Address:     "10.0.0.1",
+		Port:        80,
+		Tags:        append([]string{"haproxy.enable=true"}, tags...),
+		Datacenter:  "dc1",
+	}
+}
+
+func TestCanaryServiceWithoutRoutingLabels(t *testing.T) {
+	config := newTestConfig(t, "entrypoints:\n  http: {}\n")
+
+	instances := map[string][]*types.ServiceInstance{
+		"web":    {taggedInstance("web", "a", "haproxy.fe.fqdn=web.example.com", "haproxy.be.canary.service=web-v2", "haproxy.be.canary.weight=10")},
+		"web-v2": {taggedInstance("web-v2", "b")},
+	}
+
+	svcs, parseErrors := services.ParseServices(instances, config)
+
+	if len(parseErrors) != 0 {
+		t.Fatalf("Got parse errors %v, expected none", parseErrors)
+	}
+
+	if len(svcs) != 1 || svcs[0].ServiceName != "web" {
+		t.Fatalf("Got %d services, expected only web", len(svcs))
+	}
+
+	serverLine(t, renderBackends(t, instances, config), services.CANARY_NODE_PREFIX+"b")
+}
+
+func TestCanaryServiceWithRoutingLabels(t *testing.T) {
+	config := newTestConfig(t, "entrypoints:\n  http: {}\n")
+
+	for _, label := range []string{"haproxy.fe.fqdn=api.example.com", "haproxy.fe.entrypoints=http"} {
+		t.Run(label, func(t *testing.T) {
+			svcs, parseErrors := services.ParseServices(map[string][]*types.ServiceInstance{
+				"web": {taggedInstance("web", "a", "haproxy.fe.fqdn=web.example.com", "haproxy.be.canary.service=api")},
+				"api": {taggedInstance("api", "b", "haproxy.fe.fqdn=api.example.com", label)},
+			}, config)
+
+			if len(parseErrors) != 1 || parseErrors[0].ServiceName != "web" || parseErrors[0].Warning {
+				t.Fatalf("Got parse errors %v, expected a single error on web", parseErrors)
+			}
+
+			if len(svcs) != 1 || svcs[0].ServiceName != "api" {
+				t.Fatalf("Got %d services, expected only api", len(svcs))
+			}
+
+			if nodes := svcs[0].Nodes; len(nodes) != 1 || nodes[0].Name != "b" {
+				t.Errorf("Expected api to keep only its own node, got %d nodes", len(nodes))
+			}
+		})
+	}
+}
+
+func TestCanaryServiceKeepsItsParseErrors(t *testing.T) {
+	config := newTestConfig(t, "entrypoints:\n  http: {}\n")
+
+	_, parseErrors := services.ParseServices(map[string][]*types.ServiceInstance{
+		"web": {taggedInstance("web", "a", "haproxy.fe.fqdn=web.example.com", "haproxy.be.canary.service=api")},
+		"api": {taggedInstance("api", "b", "haproxy.fe.entrypoints=unknown")},
+	}, config)
+
+	var serviceNames []string
+	for _, parseError := range parseErrors {
+		serviceNames = append(serviceNames, parseError.ServiceName)
+	}
+
+	if strings.Join(serviceNames, ",") != "api,web" {
+		t.Errorf("Got parse errors on %v, expected on api and web", serviceNames)
+	}
+}
+
+func TestRemoteNodesAreNamedAfterTheirDatacenter(t *testing.T) {
+	config := newTestConfig(t, "entrypoints:\n  http: {}\nconsul:\n  datacenters: [dc2]\n")
+
+	instances := providers.CatalogToInstances(map[string][]*capi.CatalogService{
+		"web": {
+			catalogEntry("node-a", "dc1", capi.HealthPassing),
+			catalogEntry("node-a", "dc2", capi.HealthPassing),
+		},
+	}, config)
+
+	instances["web"][1].Remote = true
+
+	backends := renderBackends(t, instances, config)
+
+	if count := strings.Count(backends, "server node-a "); count != 1 {
+		t.Errorf("Expected a single server node-a, got %d in:\n%s", count, backends)
+	}
+
+	if line := serverLine(t, backends, "node-a.dc2"); !strings.HasSuffix(line, " backup") {
+		t.Errorf("Expected the remote server to be a backup, got %q", line)
+	}
+}
+
+func TestPathRedirectLoops(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		expected bool
+	}{
+		{name: "prefix", tags: []string{"haproxy.fe.redirect.paths.app.prefix=/old", "haproxy.fe.redirect.paths.app.location=/new"}, expected: true},
+		{name: "prefix loop", tags: []string{"haproxy.fe.redirect.paths.app.prefix=/", "haproxy.fe.redirect.paths.app.location=/app/"}, expected: false},
+		{name: "regex", tags: []string{"haproxy.fe.redirect.paths.app.regex=^/v1/", "haproxy.fe.redirect.paths.app.location=/api/v1/"}, expected: true},
+		{name: "regex loop", tags: []string{"haproxy.fe.redirect.paths.app.regex=/v1/", "haproxy.fe.redirect.paths.app.location=/api/v1/"}, expected: false},
+	}
+
+	config := newTestConfig(t, "entrypoints:\n  http: {}\n")
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svcs, parseErrors := services.ParseServices(map[string][]*types.ServiceInstance{
+				"web": {taggedInstance("web", "a", append([]string{"haproxy.fe.fqdn=web.example.com"}, test.tags...)...)},
+			}, config)
+
+			if rendered := len(svcs) == 1; rendered != test.expected {
+				t.Errorf("Got rendered %t, expected %t, with parse errors %v", rendered, test.expected, parseErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+const (
+	DEFAULT_RATE_LIMIT_PERIOD = 10 * time.Second
+	DEFAULT_RATE_LIMIT_STATUS = 429
+	RATE_LIMIT_TABLE_SIZE     = "100k"
+)
+
+func validateRateLimitConfig(config *types.RateLimitConfiguration) error {
+	if config.Requests != nil && *config.Requests < 0 {
+		return fmt.Errorf("Rate limit requests must not be negative.")
+	}
+
+	// HAProxy counts in milliseconds, so shorter periods would round to 0.
+	if config.Period != 0 && time.Duration(config.Period) < time.Millisecond {
+		return fmt.Errorf("Rate limit period must be at least 1ms, got %s", config.Period)
+	}
+
+	if config.Key != "" {
+		if _, ok := configuration.RateLimitKeyFetch(config.Key); !ok {
+			return fmt.Errorf("Invalid rate limit key specified, expected one of src, header:<name>, or cookie:<name>, got %s", config.Key)
+		}
+	}
+
+	if config.Status != nil && (*config.Status < 400 || *config.Status > 599) {
+		return fmt.Errorf("Invalid rate limit status %d, expected a 4xx or 5xx status", *config.Status)
+	}
+
+	return nil
+}
+
+// buildRateLimit renders the stick table and rules that rate limit the
+// clients of the backend of a service for an entrypoint. The labels of
+// the service take precedence over the defaults of the entrypoint.
+func buildRateLimit(service *types.Service, entryPointConfig *configuration.EntrypointConfig) string {
+	requests := 0
+	period := DEFAULT_RATE_LIMIT_PERIOD
+	key := configuration.RateLimitKeySource
+	status := DEFAULT_RATE_LIMIT_STATUS
+
+	if entryPointConfig != nil && entryPointConfig.RateLimit != nil {
+		defaults := entryPointConfig.RateLimit
+
+		if defaults.Requests != nil {
+			requests = *defaults.Requests
+		}
+
+		if defaults.Period != nil {
+			period = *defaults.Period
+		}
+
+		if defaults.Key != "" {
+			key = defaults.Key
+		}
+
+		if defaults.Status != nil {
+			status = *defaults.Status
+		}
+	}
+
+	if labels := service.Config.Be.RateLimit; labels != nil {
+		if labels.Requests != nil {
+			requests = *labels.Requests
+		}
+
+		if labels.Period != 0 {
+			period = time.Duration(labels.Period)
+		}
+
+		if labels.Key != "" {
+			key = labels.Key
+		}
+
+		if labels.Status != nil {
+			status = *labels.Status
+		}
+	}
+
+	if requests == 0 {
+		return ""
+	}
+
+	// Keys are validated along with the labels and the configuration.
+	fetch, _ := configuration.RateLimitKeyFetch(key)
+
+	tableType := "string len 128"
+	if key == configuration.RateLimitKeySource {
+		tableType = "ip"
+	}
+
+	var result string
+
+	result += fmt.Sprintf("  stick-table type %s size %s expire %s store http_req_rate(%s)\n", tableType, RATE_LIMIT_TABLE_SIZE, haproxyDuration(period), haproxyDuration(period))
+	result += fmt.Sprintf("  http-request track-sc0 %s\n", fetch)
+	result += fmt.Sprintf("  http-request deny deny_status %d if { sc_http_req_rate(0) gt %d }\n", status, requests)
+
+	return result
+}
+
+// haproxyDuration formats a duration in HAProxy's time format.
+func haproxyDuration(duration time.Duration) string {
+	if duration%time.Second == 0 {
+		return fmt.Sprintf("%ds", duration/time.Second)
+	}
+
+	return fmt.Sprintf("%dms", duration.Milliseconds())
+}
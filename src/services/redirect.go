@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+const DEFAULT_REDIRECT_CODE = 302
+
+var redirectCodes = []int{301, 302, 303, 307, 308}
+
+func validateRedirectConfig(config *types.RedirectConfiguration, fqdns []string) error {
+	config.Scheme = strings.ToLower(config.Scheme)
+
+	if config.Scheme != "" && config.Scheme != "http" && config.Scheme != "https" {
+		return fmt.Errorf("Invalid redirect scheme specified, expected one of http, or https, got %s", config.Scheme)
+	}
+
+	if strings.ContainsAny(config.Host, " \t'\"/") {
+		return fmt.Errorf("Invalid redirect host %s", config.Host)
+	}
+
+	// The redirect would apply to its own target again.
+	if slices.ContainsFunc(fqdns, func(fqdn string) bool { return strings.EqualFold(fqdn, config.Host) }) {
+		return fmt.Errorf("Redirect host %s is a FQDN of the service itself, which would redirect in a loop. Use the redirect scheme alone to upgrade to HTTPS.", config.Host)
+	}
+
+	for name, path := range config.Paths {
+		if path == nil || (path.Prefix == "") == (path.Regex == "") {
+			return fmt.Errorf("Path redirect %s must specify either a prefix or a regex.", name)
+		}
+
+		if path.Location == "" {
+			return fmt.Errorf("Path redirect %s must specify a location.", name)
+		}
+
+		// Patterns and locations are rendered within quotes.
+		if strings.ContainsAny(path.Prefix+path.Regex+path.Location, "'\"") {
+			return fmt.Errorf("Path redirect %s must not contain quotes.", name)
+		}
+
+		// Converter arguments end at commas and closing parentheses.
+		if strings.ContainsAny(path.Prefix+path.Regex+path.Location, ",)") {
+			return fmt.Errorf("Path redirect %s must not contain commas or closing parentheses.", name)
+		}
+
+		// The redirect would apply to its own location again.
+		if path.Prefix != "" && strings.HasPrefix(path.Location, path.Prefix) {
+			return fmt.Errorf("Path redirect %s location %s starts with its own prefix %s, which would redirect in a loop.", name, path.Location, path.Prefix)
+		}
+
+		if path.Regex != "" {
+			regex, err := regexp.Compile(path.Regex)
+			if err != nil {
+				return fmt.Errorf("Path redirect %s has an invalid regex: %w", name, err)
+			}
+
+			if regex.MatchString(path.Location) {
+				return fmt.Errorf("Path redirect %s location %s matches its own regex %s, which would redirect in a loop.", name, path.Location, path.Regex)
+			}
+		}
+
+		if path.Code == 0 {
+			path.Code = DEFAULT_REDIRECT_CODE
+		}
+
+		if !slices.Contains(redirectCodes, path.Code) {
+			return fmt.Errorf("Invalid redirect code %d for path redirect %s, expected one of 301, 302, 303, 307, or 308", path.Code, name)
+		}
+	}
+
+	return nil
+}
+
+// buildRedirectsForEntrypoint renders the redirects of a service,
+// they only apply to the requests of the service and must come
+// before every use_backend rule.
+func buildRedirectsForEntrypoint(entryPoint string, service *types.Service) string {
+	redirect := service.Config.Fe.Redirect
+	if redirect == nil || !slices.Contains(service.Config.Fe.EntryPoints, entryPoint) {
+		return ""
+	}
+
+	condition := fmt.Sprintf("{ hdr(host) -i %s }", strings.Join(service.Config.Fe.Fqdn, " "))
+
+	if service.Config.Fe.PathPrefix != "" {
+		condition += fmt.Sprintf(" { path_beg %s }", service.Config.Fe.PathPrefix)
+	}
+
+	code := 301
+	if redirect.Permanent != nil && !*redirect.Permanent {
+		code = 302
+	}
+
+	var result string
+
+	switch {
+	case redirect.Host != "" && redirect.Scheme != "":
+		result += fmt.Sprintf("  http-request redirect prefix %s://%s code %d if %s\n", redirect.Scheme, redirect.Host, code, condition)
+	case redirect.Host != "":
+		result += fmt.Sprintf("  http-request redirect prefix %%[ssl_fc,iif(https,http)]://%s code %d if %s\n", redirect.Host, code, condition)
+	case redirect.Scheme == "https":
+		result += fmt.Sprintf("  http-request redirect scheme https code %d if %s !{ ssl_fc }\n", code, condition)
+	case redirect.Scheme == "http":
+		result += fmt.Sprintf("  http-request redirect scheme http code %d if %s { ssl_fc }\n", code, condition)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(redirect.Paths)) {
+		path := redirect.Paths[name]
+
+		regex := path.Regex
+		if path.Prefix != "" {
+			regex = "^" + regexp.QuoteMeta(path.Prefix)
+		}
+
+		result += fmt.Sprintf("  http-request redirect location %%[pathq,'regsub(\"%s\",\"%s\")'] code %d if %s { pathq -m reg '%s' }\n", regex, path.Location, path.Code, condition, regex)
+	}
+
+	return result
+}
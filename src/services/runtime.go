@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+
+	"github.rbx.com/roblox/roblox-load-balancer/configuration"
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+// BuildRuntimeCommands builds the HAProxy Runtime API commands that turn
+// the servers of the previous services into the servers of the current services.
+//
+// It returns false if anything other than the nodes changed, such as
+// a service being added or removed, its labels, its number of slots,
+// the slots of backup nodes, or the nodes in slots of a service with
+// persistence cookies, in which case HAProxy must be reloaded
+// instead.
+func BuildRuntimeCommands(previous, current []*types.Service, config *configuration.Config) ([]string, bool) {
+	if len(previous) != len(current) {
+		return nil, false
+	}
+
+	previousServices := make(map[string]*types.Service, len(previous))
+	for _, service := range previous {
+		previousServices[service.ServiceName] = service
+	}
+
+	var commands []string
+
+	for _, service := range current {
+		previousService, ok := previousServices[service.ServiceName]
+		if !ok || previousService.Slots != service.Slots || previousService.Config.Hash() != service.Config.Hash() {
+			return nil, false
+		}
+
+		for _, entryPoint := range service.Config.Fe.EntryPoints {
+			backend := fmt.Sprintf("%s.%s", service.ServiceName, entryPoint)
+
+			backendCommands, ok := buildRuntimeCommandsForBackend(backend, previousService, service)
+			if !ok {
+				return nil, false
+			}
+
+			commands = append(commands, backendCommands...)
+		}
+	}
+
+	return commands, true
+}
+
+func buildRuntimeCommandsForBackend(backend string, previous, current *types.Service) ([]string, bool) {
+	previousNodes := make(map[int]*types.ServiceNode, len(previous.Nodes))
+	for _, node := range previous.Nodes {
+		previousNodes[node.Slot] = node
+	}
+
+	currentNodes := make(map[int]*types.ServiceNode, len(current.Nodes))
+	for _, node := range current.Nodes {
+		currentNodes[node.Slot] = node
+	}
+
+	var commands []string
+
+	for slot := 1; slot <= current.Slots; slot++ {
+		server := fmt.Sprintf("%s/%s", backend, SlotServerName(slot))
+
+		previousNode, hadNode := previousNodes[slot]
+		currentNode, hasNode := currentNodes[slot]
+
+		// The backup flag of a server cannot be changed at runtime.
+		if (hadNode && previousNode.Backup) != (hasNode && currentNode.Backup) {
+			return nil, false
+		}
+
+		// Neither can the persistence cookie of a server.
+		if hasNode && currentNode.Cookie != "" && (!hadNode || previousNode.Cookie != currentNode.Cookie) {
+			return nil, false
+		}
+
+		if !hasNode {
+			if hadNode && !previousNode.Disabled {
+				commands = append(commands, fmt.Sprintf("disable server %s", server))
+			}
+
+			continue
+		}
+
+		if !hadNode || previousNode.Address != currentNode.Address || previousNode.Port != currentNode.Port {
+			commands = append(commands, fmt.Sprintf("set server %s addr %s port %d", server, currentNode.Address, currentNode.Port))
+		}
+
+		// Free slots keep the weight of the node they last had.
+		if !hadNode || previousNode.Weight != currentNode.Weight {
+			commands = append(commands, fmt.Sprintf("set weight %s %d", server, currentNode.Weight))
+		}
+
+		wasEnabled := hadNode && !previousNode.Disabled
+
+		if currentNode.Disabled && wasEnabled {
+			commands = append(commands, fmt.Sprintf("disable server %s", server))
+		} else if !currentNode.Disabled && !wasEnabled {
+			commands = append(commands, fmt.Sprintf("enable server %s", server))
+		}
+	}
+
+	return commands, true
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
+)
+
+const (
+	SLOT_SERVER_PREFIX       = "srv"
+	SLOT_PLACEHOLDER_ADDRESS = "127.0.0.1:1"
+)
+
+// SlotServerName gets the HAProxy server name of a slot.
+func SlotServerName(slot int) string {
+	return fmt.Sprintf("%s%d", SLOT_SERVER_PREFIX, slot)
+}
+
+// AssignSlots assigns a server slot to every node of the current services.
+//
+// Nodes keep the slot they had in the previous services, new nodes
+// take the lowest free slot. A service only grows its number of slots,
+// in blocks of slotBlockSize, when it runs out of free slots.
+func AssignSlots(previous, current []*types.Service, slotBlockSize int) {
+	previousServices := make(map[string]*types.Service, len(previous))
+	for _, service := range previous {
+		previousServices[service.ServiceName] = service
+	}
+
+	for _, service := range current {
+		previousSlots := make(map[string]int)
+
+		if previousService, ok := previousServices[service.ServiceName]; ok {
+			service.Slots = previousService.Slots
+
+			for _, node := range previousService.Nodes {
+				previousSlots[node.Name] = node.Slot
+			}
+		}
+
+		if len(service.Nodes) > service.Slots {
+			service.Slots = (len(service.Nodes) + slotBlockSize - 1) / slotBlockSize * slotBlockSize
+		}
+
+		usedSlots := make(map[int]bool, len(service.Nodes))
+
+		for _, node := range service.Nodes {
+			slot, ok := previousSlots[node.Name]
+			if !ok || slot > service.Slots || usedSlots[slot] {
+				node.Slot = 0
+
+				continue
+			}
+
+			node.Slot = slot
+			usedSlots[slot] = true
+		}
+
+		nextSlot := 1
+
+		for _, node := range service.Nodes {
+			if node.Slot != 0 {
+				continue
+			}
+
+			for usedSlots[nextSlot] {
+				nextSlot++
+			}
+
+			node.Slot = nextSlot
+			usedSlots[nextSlot] = true
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package services
 
 import (
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
+
+	"github.rbx.com/roblox/roblox-load-balancer/services/types"
 )
 
 func tagsToNeutralLabels(tags []string, prefix string) map[string]string {
@@ -24,3 +29,81 @@ func tagsToNeutralLabels(tags []string, prefix string) map[string]string {
 
 	return labels
 }
+
+// metaToNeutralLabels converts the metadata keys with the prefix to labels.
+//
+// Consul only allows alphanumeric, - and _ characters in metadata
+// keys, so - is accepted as a separator as well as ., e.g.
+// haproxy-fe-pathprefix is the same label as haproxy.fe.pathprefix
+func metaToNeutralLabels(meta map[string]string, prefix string) map[string]string {
+	var labels map[string]string
+
+	for key, value := range meta {
+		var path string
+
+		if rest, ok := strings.CutPrefix(key, prefix+"."); ok {
+			path = rest
+		} else if rest, ok := strings.CutPrefix(key, prefix+"-"); ok {
+			path = strings.ReplaceAll(rest, "-", ".")
+		} else {
+			continue
+		}
+
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+
+		labels["haproxy."+path] = value
+	}
+
+	return labels
+}
+
+// instanceLabels gets the labels of an instance from both its tags and
+// its metadata. Tags take precedence over metadata, labels set to different
+// values by both are reported as conflicts.
+func instanceLabels(instance *types.ServiceInstance, prefix string) (map[string]string, []error) {
+	labels := tagsToNeutralLabels(instance.Tags, prefix)
+	metaLabels := metaToNeutralLabels(instance.Meta, prefix)
+
+	if len(metaLabels) == 0 {
+		return labels, nil
+	}
+
+	if labels == nil {
+		labels = make(map[string]string, len(metaLabels))
+	}
+
+	// Labels are case insensitive.
+	tagKeys := make(map[string]string, len(labels))
+	for key := range labels {
+		tagKeys[strings.ToLower(key)] = key
+	}
+
+	var conflicts []error
+
+	for _, key := range slices.Sorted(maps.Keys(metaLabels)) {
+		value := metaLabels[key]
+
+		tagKey, ok := tagKeys[strings.ToLower(key)]
+		if !ok {
+			labels[key] = value
+
+			continue
+		}
+
+		if labels[tagKey] != value {
+			conflicts = append(conflicts, fmt.Errorf("Label %s is set to %q by a tag and to %q by metadata, using the tag", tagKey, labels[tagKey], value))
+		}
+	}
+
+	return labels, conflicts
+}
+
+// LookupInstanceLabel looks a label of an instance up, from either
+// its tags or its metadata, e.g. haproxy.be.preparedQuery
+func LookupInstanceLabel(instance *types.ServiceInstance, prefix string, key string) (string, bool) {
+	labels, _ := instanceLabels(instance, prefix)
+
+	return lookupLabel(labels, key)
+}
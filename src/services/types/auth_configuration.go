@@ -0,0 +1,31 @@
+package types
+
+// AuthConfiguration represents the HTTP basic
+// auth required to reach a service.
+type AuthConfiguration struct {
+	// Userlist is the name of the userlist of
+	// the static configuration to check against.
+	Userlist string `json:"userlist"`
+
+	// Realm is the realm of the auth challenge.
+	//
+	// Defaults to the name of the service
+	Realm string `json:"realm"`
+}
+
+// Hash computes a hash of the AuthConfiguration
+func (ac *AuthConfiguration) Hash() uint64 {
+	var hash uint64 = 17
+
+	hash = hash*31 + uint64(len(ac.Userlist))
+	for i := 0; i < len(ac.Userlist); i++ {
+		hash = hash*31 + uint64(ac.Userlist[i])
+	}
+
+	hash = hash*31 + uint64(len(ac.Realm))
+	for i := 0; i < len(ac.Realm); i++ {
+		hash = hash*31 + uint64(ac.Realm[i])
+	}
+
+	return hash
+}
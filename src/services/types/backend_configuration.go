@@ -1,5 +1,10 @@
 package types
 
+import (
+	"maps"
+	"slices"
+)
+
 // BackendConfiguration represents the configuration
 // for the backend of a HAProxy service (such as load balancing
 // or health checks).
@@ -7,24 +12,81 @@ type BackendConfiguration struct {
 	// Balance is the load balancing mode.
 	//
 	// Defaults to roundrobin
-	Balance string
+	Balance string `json:"balance"`
 
 	// HashType is the type of hash to use.
 	//
 	// Defaults to consistent
-	HashType string
+	HashType string `json:"hashType"`
 
 	// BlockedPaths is a list of directly blocked paths.
-	BlockedPaths []string
+	BlockedPaths []string `json:"blockedPaths"`
 
 	// BlockedPaths_Beg is a list of blocked path prefixes.
-	BlockedPaths_Beg []string
+	BlockedPaths_Beg []string `json:"blockedPathsBeg"`
 
 	// Del_Headers is a list of headers to delete from the request.
-	Del_Headers []string
+	Del_Headers []string `json:"delHeaders"`
 
 	// SetHostHeader sets the host header to the specified value.
-	SetHostHeader string
+	SetHostHeader string `json:"setHostHeader"`
+
+	// PreparedQuery is the name or ID of the Consul prepared
+	// query the nodes of the backend come from, instead of
+	// the instances registered in the Catalog.
+	PreparedQuery string `json:"preparedQuery"`
+
+	// Failover determines if the backend fails over to nodes
+	// in remote datacenters.
+	//
+	// Defaults to config.Consul.CrossDatacenterFailover
+	Failover *bool `json:"failover"`
+
+	// Datacenters is the list of remote datacenters to fail
+	// over to, in order of preference. Nodes of the first
+	// datacenter with a healthy node are used as backups,
+	// datacenters that are not listed are never used.
+	//
+	// Defaults to config.Consul.Datacenters
+	Datacenters []string `json:"datacenters"`
+
+	// Canary is the canary split of the traffic of the backend.
+	Canary *CanaryConfiguration `json:"canary"`
+
+	// Cookie is the cookie based persistence of the sessions
+	// of the backend, sessions are not persisted if unset.
+	Cookie *CookieConfiguration `json:"cookie" label:"allowEmpty"`
+
+	// RateLimit is the rate limit of the clients of the
+	// backend, it overrides the rate limit of the entrypoint.
+	RateLimit *RateLimitConfiguration `json:"rateLimit"`
+
+	// AllowList is the list of IP addresses or CIDRs of the
+	// clients allowed to reach the backend, every client if empty.
+	AllowList []string `json:"allowList"`
+
+	// DenyList is the list of IP addresses or CIDRs of the
+	// clients denied from reaching the backend.
+	DenyList []string `json:"denyList"`
+
+	// Auth is the HTTP basic auth required to reach
+	// the backend, no auth is required if unset.
+	Auth *AuthConfiguration `json:"auth"`
+
+	// HeaderPresets is a list of presets of response
+	// headers to set, in order of precedence.
+	HeaderPresets []string `json:"headerPresets"`
+
+	// SetResponseHeaders is a map of response
+	// header to the value to set it to.
+	SetResponseHeaders map[string]string `json:"setResponseHeaders"`
+
+	// AddResponseHeaders is a map of response
+	// header to the value to add to it.
+	AddResponseHeaders map[string]string `json:"addResponseHeaders"`
+
+	// DelResponseHeaders is a list of headers to delete from the response.
+	DelResponseHeaders []string `json:"delResponseHeaders"`
 }
 
 // Hash computes a hash of the BackendConfiguration
@@ -70,5 +132,99 @@ func (bc *BackendConfiguration) Hash() uint64 {
 		hash = hash*31 + uint64(bc.SetHostHeader[i])
 	}
 
+	hash = hash*31 + uint64(len(bc.PreparedQuery))
+	for i := 0; i < len(bc.PreparedQuery); i++ {
+		hash = hash*31 + uint64(bc.PreparedQuery[i])
+	}
+
+	if bc.Failover != nil {
+		hash = hash*31 + 1
+
+		if *bc.Failover {
+			hash = hash*31 + 1
+		}
+	}
+
+	hash = hash*31 + uint64(len(bc.Datacenters))
+	for _, datacenter := range bc.Datacenters {
+		hash = hash*31 + uint64(len(datacenter))
+		for i := 0; i < len(datacenter); i++ {
+			hash = hash*31 + uint64(datacenter[i])
+		}
+	}
+
+	if bc.Canary != nil {
+		hash = hash*31 + bc.Canary.Hash()
+	}
+
+	if bc.Cookie != nil {
+		hash = hash*31 + bc.Cookie.Hash()
+	}
+
+	if bc.RateLimit != nil {
+		hash = hash*31 + bc.RateLimit.Hash()
+	}
+
+	hash = hash*31 + uint64(len(bc.AllowList))
+	for _, address := range bc.AllowList {
+		hash = hash*31 + uint64(len(address))
+		for i := 0; i < len(address); i++ {
+			hash = hash*31 + uint64(address[i])
+		}
+	}
+
+	hash = hash*31 + uint64(len(bc.DenyList))
+	for _, address := range bc.DenyList {
+		hash = hash*31 + uint64(len(address))
+		for i := 0; i < len(address); i++ {
+			hash = hash*31 + uint64(address[i])
+		}
+	}
+
+	if bc.Auth != nil {
+		hash = hash*31 + bc.Auth.Hash()
+	}
+
+	hash = hash*31 + uint64(len(bc.HeaderPresets))
+	for _, preset := range bc.HeaderPresets {
+		hash = hash*31 + uint64(len(preset))
+		for i := 0; i < len(preset); i++ {
+			hash = hash*31 + uint64(preset[i])
+		}
+	}
+
+	hash = hash*31 + hashHeaders(bc.SetResponseHeaders)
+	hash = hash*31 + hashHeaders(bc.AddResponseHeaders)
+
+	hash = hash*31 + uint64(len(bc.DelResponseHeaders))
+	for _, header := range bc.DelResponseHeaders {
+		hash = hash*31 + uint64(len(header))
+		for i := 0; i < len(header); i++ {
+			hash = hash*31 + uint64(header[i])
+		}
+	}
+
+	return hash
+}
+
+// hashHeaders computes a hash of a map of header to value.
+func hashHeaders(headers map[string]string) uint64 {
+	var hash uint64 = 17
+
+	hash = hash*31 + uint64(len(headers))
+	for _, header := range slices.Sorted(maps.Keys(headers)) {
+		hash = hash*31 + uint64(len(header))
+		for i := 0; i < len(header); i++ {
+			hash = hash*31 + uint64(header[i])
+		}
+
+		value := headers[header]
+
+		hash = hash*31 + uint64(len(value))
+		for i := 0; i < len(value); i++ {
+			hash = hash*31 + uint64(value[i])
+		}
+	}
+
 	return hash
 }
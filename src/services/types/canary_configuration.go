@@ -0,0 +1,41 @@
+package types
+
+// CanaryConfiguration represents the canary split
+// of the traffic of a service.
+type CanaryConfiguration struct {
+	// Service is the name of the service that receives
+	// the canary traffic.
+	Service string `json:"service"`
+
+	// Tag is the tag of the instances of the service
+	// itself that receive the canary traffic.
+	Tag string `json:"tag"`
+
+	// Weight is the percentage of the traffic sent to the
+	// canary instances, from 0 to 100. It can also be set
+	// by the canary instances themselves, which takes
+	// precedence.
+	//
+	// Defaults to 0
+	Weight *int `json:"weight"`
+}
+
+// Hash computes a hash of the CanaryConfiguration
+//
+// The weight is left out, as it only changes server
+// weights which can be updated at runtime.
+func (cc *CanaryConfiguration) Hash() uint64 {
+	var hash uint64 = 17
+
+	hash = hash*31 + uint64(len(cc.Service))
+	for i := 0; i < len(cc.Service); i++ {
+		hash = hash*31 + uint64(cc.Service[i])
+	}
+
+	hash = hash*31 + uint64(len(cc.Tag))
+	for i := 0; i < len(cc.Tag); i++ {
+		hash = hash*31 + uint64(cc.Tag[i])
+	}
+
+	return hash
+}
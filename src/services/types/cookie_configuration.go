@@ -0,0 +1,68 @@
+package types
+
+import paerser "github.com/traefik/paerser/types"
+
+// CookieConfiguration represents the cookie based
+// persistence of the sessions of a service.
+type CookieConfiguration struct {
+	// Name is the name of the cookie.
+	//
+	// Defaults to SERVERID
+	Name string `json:"name"`
+
+	// Mode is how the cookie is set, one of insert,
+	// rewrite, or prefix.
+	//
+	// Defaults to insert
+	Mode string `json:"mode"`
+
+	// NoCache marks responses that set the
+	// cookie as not cacheable.
+	NoCache bool `json:"nocache"`
+
+	// HttpOnly sets the HttpOnly attribute of the cookie.
+	HttpOnly bool `json:"httponly"`
+
+	// Secure sets the Secure attribute of the cookie.
+	Secure bool `json:"secure"`
+
+	// MaxIdle is how long a session may be idle before its cookie
+	// is ignored, in seconds if it has no unit, only in insert mode.
+	MaxIdle paerser.Duration `json:"maxidle"`
+
+	// MaxLife is how long a session may last before its cookie
+	// is ignored, in seconds if it has no unit, only in insert mode.
+	MaxLife paerser.Duration `json:"maxlife"`
+}
+
+// Hash computes a hash of the CookieConfiguration
+func (cc *CookieConfiguration) Hash() uint64 {
+	var hash uint64 = 17
+
+	hash = hash*31 + uint64(len(cc.Name))
+	for i := 0; i < len(cc.Name); i++ {
+		hash = hash*31 + uint64(cc.Name[i])
+	}
+
+	hash = hash*31 + uint64(len(cc.Mode))
+	for i := 0; i < len(cc.Mode); i++ {
+		hash = hash*31 + uint64(cc.Mode[i])
+	}
+
+	if cc.NoCache {
+		hash = hash*31 + 1
+	}
+
+	if cc.HttpOnly {
+		hash = hash*31 + 2
+	}
+
+	if cc.Secure {
+		hash = hash*31 + 3
+	}
+
+	hash = hash*31 + uint64(cc.MaxIdle)
+	hash = hash*31 + uint64(cc.MaxLife)
+
+	return hash
+}
@@ -6,29 +6,33 @@ package types
 type FrontendConfiguration struct {
 	// Fqdn is a list of hosts to use to resolve
 	// a backend.
-	Fqdn []string
+	Fqdn []string `json:"fqdn"`
 
 	// BlockedPaths is a list of directly blocked paths.
 	// The difference between FE and BE blocked paths
 	// is that if any of these paths hit then this will
 	// be treated as an unknown backend vs 403.
-	BlockedPaths []string
+	BlockedPaths []string `json:"blockedPaths"`
 
 	// BlockedPaths_Beg is a list of blocked path prefixes.
 	// The difference between FE and BE blocked paths
 	// is that if any of these paths hit then this will
 	// be treated as an unknown backend vs 403.
-	BlockedPaths_Beg []string
+	BlockedPaths_Beg []string `json:"blockedPathsBeg"`
 
 	// EntryPoints is the list of entrypoints that this
 	// service can be called from.
-	EntryPoints []string
+	EntryPoints []string `json:"entryPoints"`
 
 	// PathPrefix is the prefix to use when routing
 	// to the backend.
 	//
 	// This will be stripped on the backend.
-	PathPrefix string
+	PathPrefix string `json:"pathPrefix"`
+
+	// Redirect is the redirects of the requests
+	// to this service, before they are routed.
+	Redirect *RedirectConfiguration `json:"redirect"`
 }
 
 // Hash computes a hash of the FrontendConfiguration
@@ -72,5 +76,9 @@ func (fc *FrontendConfiguration) Hash() uint64 {
 		hash = hash*31 + uint64(fc.PathPrefix[i])
 	}
 
+	if fc.Redirect != nil {
+		hash = hash*31 + fc.Redirect.Hash()
+	}
+
 	return hash
 }
@@ -0,0 +1,62 @@
+package types
+
+// ServiceInstance is a provider-neutral instance of a
+// service, as returned by service discovery.
+type ServiceInstance struct {
+	// ID is the unique ID of this instance.
+	ID string `json:"id"`
+
+	// ServiceName is the name of the service.
+	ServiceName string `json:"serviceName"`
+
+	// Name is the name of the server rendered for this instance.
+	Name string `json:"name"`
+
+	// Address is the address of this instance.
+	Address string `json:"address"`
+
+	// Port is the port of this instance.
+	Port int `json:"port"`
+
+	// Tags are the tags of this instance, routing labels
+	// are parsed from the tags with the configured prefix.
+	Tags []string `json:"tags"`
+
+	// Datacenter is the datacenter of this instance.
+	Datacenter string `json:"datacenter"`
+
+	// Remote determines if this instance is in a datacenter
+	// other than the local one.
+	Remote bool `json:"remote"`
+
+	// Meta is the metadata of this instance.
+	Meta map[string]string `json:"meta"`
+
+	// Weight is the weight of this instance relative to the
+	// other instances of the service, from 0 to 256.
+	//
+	// Defaults to 1
+	Weight *int `json:"weight,omitempty"`
+
+	// WeightPercent is the percentage of its weight this instance
+	// keeps, such as when its health checks are in warning.
+	//
+	// Defaults to 100
+	WeightPercent *int `json:"weightPercent,omitempty"`
+
+	// ModifyIndex is the index this instance was last
+	// modified at, if the provider keeps track of it.
+	ModifyIndex uint64 `json:"modifyIndex"`
+
+	// Unhealthy determines if this instance
+	// is failing its health checks.
+	Unhealthy bool `json:"unhealthy"`
+
+	// Error is a discovery error of this instance, its
+	// service is skipped and reported with the error.
+	Error string `json:"error,omitempty"`
+
+	// Warning is a discovery warning of this instance,
+	// its service is reported with the warning.
+	Warning string `json:"warning,omitempty"`
+}
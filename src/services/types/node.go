@@ -3,13 +3,39 @@ package types
 // ServiceNode represent a node within a service.
 type ServiceNode struct {
 	// Name is the name of this node.
-	Name string
+	Name string `json:"name"`
 
 	// Address is the address of this node.
-	Address string
+	Address string `json:"address"`
 
 	// Port is the port of this node.
-	Port int
+	Port int `json:"port"`
+
+	// Disabled determines if this node is failing its
+	// health checks and must not receive traffic.
+	Disabled bool `json:"disabled"`
+
+	// Weight is the HAProxy weight of this node.
+	Weight int `json:"weight"`
+
+	// Datacenter is the datacenter of this node.
+	Datacenter string `json:"datacenter"`
+
+	// Backup determines if this node is in a remote datacenter
+	// and only receives traffic when no local node is available.
+	Backup bool `json:"backup"`
+
+	// Canary determines if this node receives
+	// the canary traffic of its service.
+	Canary bool `json:"canary"`
+
+	// Cookie is the persistence cookie value of this node,
+	// derived from its instance so that it is stable.
+	Cookie string `json:"cookie"`
+
+	// Slot is the server slot of this node within
+	// its backends when the Runtime API is enabled.
+	Slot int `json:"slot"`
 }
 
 // Hash computes a hash of the ServiceNode
@@ -28,5 +54,31 @@ func (sn *ServiceNode) Hash() uint64 {
 
 	hash = hash*31 + uint64(sn.Port)
 
+	if sn.Disabled {
+		hash = hash*31 + 1
+	}
+
+	hash = hash*31 + uint64(sn.Weight)
+
+	hash = hash*31 + uint64(len(sn.Datacenter))
+	for i := 0; i < len(sn.Datacenter); i++ {
+		hash = hash*31 + uint64(sn.Datacenter[i])
+	}
+
+	if sn.Backup {
+		hash = hash*31 + 1
+	}
+
+	if sn.Canary {
+		hash = hash*31 + 1
+	}
+
+	hash = hash*31 + uint64(len(sn.Cookie))
+	for i := 0; i < len(sn.Cookie); i++ {
+		hash = hash*31 + uint64(sn.Cookie[i])
+	}
+
+	hash = hash*31 + uint64(sn.Slot)
+
 	return hash
 }
@@ -0,0 +1,48 @@
+package types
+
+import paerser "github.com/traefik/paerser/types"
+
+// RateLimitConfiguration represents the rate limit of the
+// clients of a service, unset fields default to the rate
+// limit of the entrypoint.
+type RateLimitConfiguration struct {
+	// Requests is the number of requests a client may make
+	// per period, clients are not limited if 0.
+	Requests *int `json:"requests"`
+
+	// Period is the period over which requests are counted,
+	// in seconds if it has no unit.
+	Period paerser.Duration `json:"period"`
+
+	// Key is what clients are tracked by, one of src,
+	// header:<name>, or cookie:<name>.
+	Key string `json:"key"`
+
+	// Status is the status of the responses to
+	// requests over the limit.
+	Status *int `json:"status"`
+}
+
+// Hash computes a hash of the RateLimitConfiguration
+func (rc *RateLimitConfiguration) Hash() uint64 {
+	var hash uint64 = 17
+
+	if rc.Requests != nil {
+		hash = hash*31 + 1
+		hash = hash*31 + uint64(*rc.Requests)
+	}
+
+	hash = hash*31 + uint64(rc.Period)
+
+	hash = hash*31 + uint64(len(rc.Key))
+	for i := 0; i < len(rc.Key); i++ {
+		hash = hash*31 + uint64(rc.Key[i])
+	}
+
+	if rc.Status != nil {
+		hash = hash*31 + 1
+		hash = hash*31 + uint64(*rc.Status)
+	}
+
+	return hash
+}
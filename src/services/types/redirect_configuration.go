@@ -0,0 +1,114 @@
+package types
+
+import (
+	"maps"
+	"slices"
+)
+
+// PathRedirectConfiguration represents a redirect of the
+// requests of a service whose path matches a prefix or a
+// regular expression.
+type PathRedirectConfiguration struct {
+	// Prefix redirects the requests whose path begins with
+	// this prefix, replacing it with Location.
+	Prefix string `json:"prefix"`
+
+	// Regex redirects the requests whose path and query
+	// string match this regular expression, replacing the
+	// match with Location, which can refer to capture groups
+	// with \1 to \9.
+	Regex string `json:"regex"`
+
+	// Location is the replacement of the prefix or the match.
+	Location string `json:"location"`
+
+	// Code is the status code of the redirect,
+	// one of 301, 302, 303, 307, or 308.
+	//
+	// Defaults to 302
+	Code int `json:"code"`
+}
+
+// Hash computes a hash of the PathRedirectConfiguration
+func (pc *PathRedirectConfiguration) Hash() uint64 {
+	var hash uint64 = 17
+
+	hash = hash*31 + uint64(len(pc.Prefix))
+	for i := 0; i < len(pc.Prefix); i++ {
+		hash = hash*31 + uint64(pc.Prefix[i])
+	}
+
+	hash = hash*31 + uint64(len(pc.Regex))
+	for i := 0; i < len(pc.Regex); i++ {
+		hash = hash*31 + uint64(pc.Regex[i])
+	}
+
+	hash = hash*31 + uint64(len(pc.Location))
+	for i := 0; i < len(pc.Location); i++ {
+		hash = hash*31 + uint64(pc.Location[i])
+	}
+
+	hash = hash*31 + uint64(pc.Code)
+
+	return hash
+}
+
+// RedirectConfiguration represents the redirects
+// of the requests of a service.
+type RedirectConfiguration struct {
+	// Scheme redirects the requests to this scheme,
+	// e.g. https to upgrade HTTP requests.
+	Scheme string `json:"scheme"`
+
+	// Host redirects the requests to this host,
+	// keeping their path and query string.
+	Host string `json:"host"`
+
+	// Permanent determines if scheme and host redirects
+	// are permanent (301) or temporary (302).
+	//
+	// Defaults to true
+	Permanent *bool `json:"permanent"`
+
+	// Paths is a map of name to the path redirects, which
+	// are evaluated in order of name after the scheme and
+	// host redirects.
+	Paths map[string]*PathRedirectConfiguration `json:"paths"`
+}
+
+// Hash computes a hash of the RedirectConfiguration
+func (rc *RedirectConfiguration) Hash() uint64 {
+	var hash uint64 = 17
+
+	hash = hash*31 + uint64(len(rc.Scheme))
+	for i := 0; i < len(rc.Scheme); i++ {
+		hash = hash*31 + uint64(rc.Scheme[i])
+	}
+
+	hash = hash*31 + uint64(len(rc.Host))
+	for i := 0; i < len(rc.Host); i++ {
+		hash = hash*31 + uint64(rc.Host[i])
+	}
+
+	if rc.Permanent != nil {
+		hash = hash*31 + 1
+
+		if *rc.Permanent {
+			hash = hash*31 + 1
+		}
+	}
+
+	hash = hash*31 + uint64(len(rc.Paths))
+	for _, name := range slices.Sorted(maps.Keys(rc.Paths)) {
+		hash = hash*31 + uint64(len(name))
+		for i := 0; i < len(name); i++ {
+			hash = hash*31 + uint64(name[i])
+		}
+
+		if rc.Paths[name] != nil {
+			hash = hash*31 + rc.Paths[name].Hash()
+		}
+	}
+
+	return hash
+}
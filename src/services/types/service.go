@@ -3,13 +3,17 @@ package types
 // Service represents a service.
 type Service struct {
 	// ServiceName is the name of the service.
-	ServiceName string
+	ServiceName string `json:"serviceName"`
 
 	// Config is the label config from Consul.
-	Config *ServiceConfig
+	Config *ServiceConfig `json:"config"`
 
 	// Nodes is the nodes of this service.
-	Nodes []*ServiceNode
+	Nodes []*ServiceNode `json:"nodes"`
+
+	// Slots is the number of server slots in the backends
+	// of this service when the Runtime API is enabled.
+	Slots int `json:"slots"`
 }
 
 // Hash computes a hash of the Service
@@ -29,5 +33,7 @@ func (s *Service) Hash() uint64 {
 		hash = hash*31 + node.Hash()
 	}
 
+	hash = hash*31 + uint64(s.Slots)
+
 	return hash
 }
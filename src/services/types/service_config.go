@@ -12,17 +12,17 @@ type ServiceConfig struct {
 	//
 	// One of: http, https, h2c (for insecure HTTP2)
 	// Default: http
-	Protocol string
+	Protocol string `json:"protocol"`
 
 	// Enable determines if this is enabled or not
 	// always true
-	Enable bool
+	Enable bool `json:"enable"`
 
 	// Fe is the frontend configuration
-	Fe *FrontendConfiguration
+	Fe *FrontendConfiguration `json:"fe"`
 
 	// Be is the backend configuration
-	Be *BackendConfiguration
+	Be *BackendConfiguration `json:"be"`
 }
 
 // Hash computes a hash of the ServiceConfig